@@ -6,8 +6,11 @@ import (
 )
 
 type action struct {
-	Type  actionType  // Kind of action (bump, quit, open inventory, etc)
-	Delta gruid.Point // direction for ActionBump
+	Type   actionType  // Kind of action (bump, quit, open inventory, etc)
+	Delta  gruid.Point `json:",omitzero"` // direction for ActionBump
+	Key    rune        `json:",omitzero"` // inventory letter for item actions
+	Target gruid.Point `json:",omitzero"` // map position for ActionTarget, ActionThrowItem and ActionOrder
+	Order  Order       `json:",omitzero"` // order for ActionOrder
 }
 
 type actionType int
@@ -25,8 +28,32 @@ const (
 	ActionIAnimate                // Start an interruptible animation.
 	ActionPlaceRoom               // Debug: place one more room on the map.
 	ActionConnectRooms            // Second pass: connect distant regions with doors.
+	ActionUseItem                 // Use the inventory item at Key.
+	ActionDropItem                // Drop the inventory item at Key.
+	ActionTarget                  // Use the ranged inventory item at Key on Target.
+	ActionDescend                 // Go down the stairs.
+	ActionAscend                  // Go up the stairs.
+	ActionOrders                  // Open the orders menu.
+	ActionOrder                   // Give Order to followers.
+	ActionEquip                   // Wear or wield the inventory item at Key.
+	ActionUnequip                 // Stop using the inventory item at Key.
+	ActionThrow                   // Choose an item to throw.
+	ActionThrowItem               // Throw the inventory item at Key at Target.
 )
 
+// recordable returns true for actions that change the game state, and that
+// are thus recorded for replays. Actions that only affect the UI are not.
+func (a action) recordable() bool {
+	switch a.Type {
+	case ActionBump, ActionWait, ActionPickup, ActionUseItem, ActionDropItem,
+		ActionTarget, ActionDescend, ActionAscend, ActionOrder, ActionEquip, ActionUnequip,
+		ActionThrowItem,
+		ActionPlaceRoom, ActionConnectRooms:
+		return true
+	}
+	return false
+}
+
 func (m *model) handleAction() gruid.Effect {
 	switch m.action.Type {
 	case ActionBump:
@@ -38,6 +65,7 @@ func (m *model) handleAction() gruid.Effect {
 		m.game.CollectMessages()
 
 	case ActionWait:
+		m.game.ECS.Spend(0, CostWait)
 		m.game.ECS.Update()
 		m.game.CollectMessages()
 
@@ -53,13 +81,59 @@ func (m *model) handleAction() gruid.Effect {
 		m.mode = modeInventoryDrop
 		m.game.CollectMessages()
 
+	case ActionThrow:
+		if !m.game.ECS.PlayerDead() {
+			m.OpenInventory("Throw item")
+			m.mode = modeInventoryThrow
+			m.game.CollectMessages()
+		}
+
+	case ActionUseItem, ActionDropItem, ActionEquip, ActionUnequip, ActionThrowItem:
+		var err error
+		switch m.action.Type {
+		case ActionUseItem:
+			err = m.game.ECS.UseItem(0, m.action.Key)
+		case ActionDropItem:
+			err = m.game.ECS.DropItem(0, m.action.Key)
+		case ActionEquip:
+			err = m.game.ECS.Equip(0, m.action.Key)
+		case ActionUnequip:
+			err = m.game.ECS.Unequip(0, m.action.Key)
+		case ActionThrowItem:
+			err = m.game.ECS.Throw(0, m.action.Key, m.action.Target)
+		}
+		if err != nil {
+			m.game.Logf(err.Error(), ColorLogSpecial)
+		}
+		m.game.ECS.Update()
+		m.game.CollectMessages()
+
+	case ActionTarget:
+		m.activateTarget(m.action.Key, m.action.Target)
+		m.game.CollectMessages()
+
 	case ActionPickup:
-		ok := m.game.PickupItem()
+		ok := m.game.ECS.Pickup(0)
 		m.game.CollectMessages()
 		if ok {
 			m.game.ECS.Update()
 		}
 
+	case ActionDescend, ActionAscend:
+		if m.game.TakeStairs(m.action.Type == ActionDescend) {
+			m.game.ECS.Update()
+		}
+		m.game.CollectMessages()
+
+	case ActionOrders:
+		if !m.game.ECS.PlayerDead() {
+			m.OpenOrders()
+		}
+
+	case ActionOrder:
+		m.game.GiveOrder(m.action.Order, m.action.Target)
+		m.game.CollectMessages()
+
 	case ActionViewMessages:
 		m.mode = modeMessageViewer
 		lines := []ui.StyledText{}
@@ -71,7 +145,7 @@ func (m *model) handleAction() gruid.Effect {
 		m.viewer.SetLines(lines)
 
 	case ActionQuit:
-		return gruid.End()
+		return m.quit()
 
 	case ActionExamine:
 		m.mode = modeExamination
@@ -88,5 +162,6 @@ func (m *model) handleAction() gruid.Effect {
 			m.ianimation = NewExampleIAnimation(p.(Position).Point)
 		}
 	}
+	m.collectAnimations()
 	return nil
 }
@@ -38,7 +38,7 @@ func NewFrameCell(cell gruid.Cell, p gruid.Point) FrameCell {
 	return FrameCell{Renderable{cell: cell, order: ROActor}, p}
 }
 
-func (g *game) NewExampleAnimation(p gruid.Point) int {
+func (g *game) NewExampleAnimation(p gruid.Point) Entity {
 	return g.ECS.Create(
 		Position{p},
 		Animation{
@@ -0,0 +1,124 @@
+// Areas of effect. Items with an AreaOfEffect component affect everything
+// within their radius of the impact point, as long as it is in view of the
+// impact point, so that walls shelter what lies behind them.
+
+package main
+
+import (
+	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/paths"
+	"codeberg.org/anaseto/gruid/rl"
+)
+
+// radiusOf returns the radius of the area of effect of item, 0 if it only
+// affects the impact point.
+func (ecs *ECS) radiusOf(item Entity) int {
+	aoe, _ := Get[AreaOfEffect](ecs, item)
+	return aoe.radius
+}
+
+// blastArea returns the points within radius of p that are in view of p,
+// starting with p itself.
+func (ecs *ECS) blastArea(p gruid.Point, radius int) []gruid.Point {
+	if radius <= 0 {
+		return []gruid.Point{p}
+	}
+	fov := rl.NewFOV(gruid.NewRange(-radius, -radius, radius+1, radius+1).Add(p).Intersect(ecs.Map.Grid.Range()))
+	passable := func(q gruid.Point) bool {
+		return ecs.Map.Grid.At(q) != Wall
+	}
+	area := []gruid.Point{p}
+	for _, q := range fov.SSCVisionMap(p, radius, passable, true) {
+		if q != p && paths.DistanceChebyshev(p, q) <= radius {
+			area = append(area, q)
+		}
+	}
+	return area
+}
+
+// blast deals the damage of item, used by e, to every entity with health in
+// area. Blasts always hit, but each victim gets its own damage roll.
+func (ecs *ECS) blast(e, item Entity, area []gruid.Point) {
+	dmg, ok := Get[Damage](ecs, item)
+	if !ok {
+		return
+	}
+	name := GetComponent[Name](ecs, item).string
+	for _, q := range area {
+		for _, v := range ecs.EntitiesAtPWith(q, Health{}) {
+			ecs.queueDamage(v, DamageEffect{source: e, amount: dmg.roll(ecs.Map.Rand), missile: name})
+		}
+	}
+}
+
+// affect applies the effects of item, other than its damage, to e: healing,
+// and the status it inflicts.
+func (ecs *ECS) affect(e, item Entity) {
+	if healing, ok := Get[Healing](ecs, item); ok {
+		ecs.hurt(e, -healing.amount)
+	}
+	if in, ok := Get[Inflicts](ecs, item); ok {
+		ecs.AddStatus(e, in.status, in.nticks, in.power)
+	}
+}
+
+// explode applies all the effects of item, used by e, to every entity with
+// health in its area of effect around p, and queues the burst for the UI.
+func (ecs *ECS) explode(e, item Entity, p gruid.Point) {
+	area := ecs.blastArea(p, ecs.radiusOf(item))
+	ecs.blast(e, item, area)
+	for _, q := range area {
+		for _, v := range ecs.EntitiesAtPWith(q, Health{}) {
+			ecs.affect(v, item)
+		}
+	}
+	ecs.queueBurst(item, p, area)
+}
+
+// queueBurst creates a Burst of the color of item over area, so that the UI
+// animates it, unless area is a single tile.
+func (ecs *ECS) queueBurst(item Entity, p gruid.Point, area []gruid.Point) {
+	if len(area) <= 1 {
+		return
+	}
+	fg := GetComponent[Renderable](ecs, item).cell.Style.Fg
+	ecs.Create(Burst{center: p, area: area, color: fg})
+}
+
+// burst returns the frames of an animation of a blast spreading from p over
+// area, as far as the player can see it.
+func (g *game) burst(p gruid.Point, area []gruid.Point, fg gruid.Color) []Frame {
+	frames := []Frame{}
+	for r := 0; ; r++ {
+		cells := []FrameCell{}
+		grown := false
+		for _, q := range area {
+			d := paths.DistanceChebyshev(p, q)
+			if d == r {
+				grown = true
+			}
+			if d <= r && g.InFOV(q) {
+				cells = append(cells, FrameCell{NewRenderableNoBg('*', fg, ROActor), q})
+			}
+		}
+		if !grown {
+			break
+		}
+		if len(cells) > 0 {
+			frames = append(frames, Frame{nticks: 1, framecells: cells})
+		}
+	}
+	return frames
+}
+
+// animate plays frames after the interruptible animation being played, if
+// any.
+func (m *model) animate(frames []Frame) {
+	if len(frames) == 0 {
+		return
+	}
+	if m.ianimation == nil {
+		m.ianimation = &Animation{}
+	}
+	m.ianimation.frames = append(m.ianimation.frames, frames...)
+}
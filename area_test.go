@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+func TestAreaOfEffect(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	impact := origin.Shift(4, 0)
+	for x := -3; x <= 3; x++ {
+		g.Map.Grid.Set(impact.Shift(x, 1), Wall)
+	}
+	near := g.NewGoblin(impact.Shift(1, -1))
+	sheltered := g.NewGoblin(impact.Shift(0, 2))
+	far := g.NewGoblin(impact.Shift(4, 0))
+	for _, e := range []Entity{near, sheltered, far} {
+		g.ECS.RemoveComponent(e, AI{})
+		g.ECS.AddComponent(e, Health{hp: 20, maxhp: 20})
+	}
+	g.NewScroll(origin)
+	h.keys("g", "i", "b")
+	if h.m.mode != modeTargeting || h.m.target.radius != 3 {
+		t.Fatalf("mode %v, want targeting with radius 3", h.m.mode)
+	}
+	h.keys("l", "l", "l", "k")
+	// The blast area is previewed while aiming, walls included.
+	grid := h.m.Draw()
+	if grid.At(impact.Shift(1, -1).Shift(1, 1)).Style.Bg != ColorTarget {
+		t.Errorf("blast area not previewed around %v", impact)
+	}
+	if grid.At(impact.Shift(0, 2).Shift(1, 1)).Style.Bg == ColorTarget {
+		t.Errorf("blast preview goes through walls")
+	}
+	// Moving the mouse off the map leaves the cursor on its edge.
+	h.mouse(gruid.Point{}, gruid.MouseMove)
+	h.m.Draw()
+	if h.m.target.pos != (gruid.Point{}) {
+		t.Errorf("cursor at %v off the map, want %v", h.m.target.pos, gruid.Point{})
+	}
+	h.mouse(impact.Shift(1, 1), gruid.MouseMove)
+	h.keys(gruid.KeyEnter)
+	if hp := GetComponent[Health](g.ECS, near).hp; hp > 16 {
+		t.Errorf("goblin in the blast at %d hp, want at most 16", hp)
+	}
+	for _, e := range []Entity{sheltered, far} {
+		if hp := GetComponent[Health](g.ECS, e).hp; hp != 20 {
+			t.Errorf("goblin out of the blast at %d hp", hp)
+		}
+	}
+	if !h.logged("Your scroll hits the goblin!") {
+		t.Errorf("blast not logged: %v", g.Log)
+	}
+	if h.m.ianimation == nil || len(h.m.ianimation.frames) < 2 {
+		t.Errorf("no burst animation")
+	}
+}
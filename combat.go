@@ -0,0 +1,174 @@
+// Attack resolution. Each attack first rolls to hit, comparing the attacker's
+// accuracy to the defender's evasion. Hits then roll their damage in the
+// attacker's range: hits that barely connect only graze, and the luckiest
+// ones are critical. Armor, that is Defense, is taken off in
+// DamageEffectSystem.
+
+package main
+
+import (
+	"math/rand/v2"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+const (
+	BaseHitChance = 80 // Chance to hit, in percent, before accuracy and evasion.
+	MinHitChance  = 5
+	MaxHitChance  = 95
+	GrazeChance   = 15 // Chance, in percent, that a hit only grazes.
+	CritChance    = 10 // Chance, in percent, that a hit is critical.
+)
+
+// hitKind tells how well an attack connected. Misses are reported right
+// away, so they never make it to a DamageEffect.
+type hitKind int
+
+const (
+	hitNormal hitKind = iota
+	hitGraze          // Half damage.
+	hitCrit           // Extra damage, through armor.
+)
+
+// roll returns a random amount of damage in the range of d.
+func (d Damage) roll(rng *rand.Rand) int {
+	if d.max <= d.min {
+		return d.min
+	}
+	return d.min + rng.IntN(d.max-d.min+1)
+}
+
+// add returns the damage range of d increased by that of bonus.
+func (d Damage) add(bonus Damage) Damage {
+	return Damage{d.min + bonus.min, d.max + bonus.max}
+}
+
+// hitChance returns the chance, in percent, that att hits def.
+func (ecs *ECS) hitChance(att, def Entity) int {
+	acc, _ := Get[Accuracy](ecs, att)
+	eva, _ := Get[Evasion](ecs, def)
+	return min(max(BaseHitChance+acc.int-eva.int, MinHitChance), MaxHitChance)
+}
+
+// attack resolves an attack of att on def dealing dmg, and queues the damage
+// effect of hits on def. missile is what was shot or thrown at def, if it was
+// not a melee attack. It returns false if the attack missed.
+func (ecs *ECS) attack(att, def Entity, dmg Damage, missile string) bool {
+	chance := ecs.hitChance(att, def)
+	r := ecs.Map.Rand.IntN(100)
+	if r >= chance {
+		ecs.Create(LogEntry{Text: ecs.missMessage(att, def, missile), Color: attackColor(def)})
+		return false
+	}
+	de := DamageEffect{source: att, amount: dmg.roll(ecs.Map.Rand), missile: missile}
+	switch {
+	case r < chance*CritChance/100:
+		de.hit = hitCrit
+		de.amount += dmg.max
+	case r >= chance*(100-GrazeChance)/100:
+		de.hit = hitGraze
+		de.amount = max(de.amount/2, 1)
+	}
+	ecs.queueDamage(def, de)
+	return true
+}
+
+// queueDamage adds de to the damage effects to apply to def. The attacker's
+// name and weapon are noted right away, since the attacker may die or be
+// deleted before the effect is applied.
+func (ecs *ECS) queueDamage(def Entity, de DamageEffect) {
+	de.attacker = ecs.nameOf(de.source)
+	if de.missile == "" {
+		de.weapon, _ = ecs.weapon(de.source)
+	}
+	dmgfx, _ := Get[DamageEffects](ecs, def)
+	dmgfx.effects = append(dmgfx.effects, de)
+	ecs.AddComponent(def, dmgfx)
+}
+
+// attackColor returns the log color of attacks on def.
+func attackColor(def Entity) gruid.Color {
+	if def == 0 {
+		return ColorLogMonsterAttack
+	}
+	return ColorLogPlayerAttack
+}
+
+// nameOf returns the name of e, or "" if it has none, e.g. because it has
+// been deleted.
+func (ecs *ECS) nameOf(e Entity) string {
+	n, _ := Get[Name](ecs, e)
+	return n.string
+}
+
+// attackerName returns how messages refer to the attacker att, whose name was
+// name when it attacked, and whether that is in the third person. missile is
+// what att shot or threw, if any.
+func attackerName(att Entity, name, missile string) (string, bool) {
+	switch {
+	case att == 0 && missile != "":
+		return "Your " + missile, true
+	case att == 0:
+		return "You", false
+	}
+	if name == "" {
+		name = "something"
+	}
+	if missile != "" {
+		return "The " + name + "'s " + missile, true
+	}
+	return "The " + name, true
+}
+
+// defenderName returns how messages refer to the defender def.
+func (ecs *ECS) defenderName(def Entity) string {
+	if def == 0 {
+		return "you"
+	}
+	return "the " + GetComponent[Name](ecs, def).string
+}
+
+func (ecs *ECS) missMessage(att, def Entity, missile string) string {
+	subject, third := attackerName(att, ecs.nameOf(att), missile)
+	verb := "miss"
+	if third {
+		verb = "misses"
+	}
+	return subject + " " + verb + " " + ecs.defenderName(def) + "."
+}
+
+// hitMessage describes the damage effect de on e.
+func (ecs *ECS) hitMessage(de DamageEffect, e Entity) string {
+	subject, third := attackerName(de.source, de.attacker, de.missile)
+	weapon := de.weapon
+	armed := weapon != ""
+	verb := "hit"
+	switch {
+	case de.hit == hitGraze:
+		verb = "graze"
+	case de.missile != "" || armed:
+		// Missiles and weapons just hit.
+	case de.source == 0:
+		verb = "punch"
+	case e == 0:
+		verb = "maul"
+	}
+	if third {
+		verb = thirdPerson(verb)
+	}
+	msg := subject + " " + verb + " " + ecs.defenderName(e)
+	if armed && de.source == 0 {
+		msg += " with your " + weapon
+	} else if armed {
+		msg += " with its " + weapon
+	}
+	switch {
+	case de.hit == hitCrit:
+		msg += "! A critical hit!"
+	case de.hit == hitGraze || (de.source != 0 && e != 0):
+		msg += "."
+	default:
+		msg += "!"
+	}
+	return msg
+}
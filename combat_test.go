@@ -0,0 +1,102 @@
+package main
+
+import "testing"
+
+func TestCombat(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	goblin := h.m.game.NewGoblin(origin.Shift(1, 0))
+	// Without AI, the wounded goblin does not flee.
+	h.ecs().RemoveComponent(goblin, AI{})
+	for i := 0; i < 20 && !Has[Dead](h.ecs(), goblin); i++ {
+		hp := GetComponent[Health](h.ecs(), goblin).hp
+		h.keys("l")
+		if got := h.playerPos(); got != origin {
+			t.Fatalf("attacking moved the player to %v", got)
+		}
+		// At most a critical hit with the sword: 7+7.
+		if now, ok := Get[Health](h.ecs(), goblin); ok && (hp-now.hp < 0 || hp-now.hp > 14) {
+			t.Errorf("goblin went from %d to %d hp", hp, now.hp)
+		}
+	}
+	if !Has[Dead](h.ecs(), goblin) {
+		t.Fatalf("goblin not dead after 20 attacks")
+	}
+	if !h.logged("You hit the goblin with your sword") {
+		t.Errorf("attack not logged: %v", h.m.game.Log)
+	}
+	if !h.logged("goblin has died!") {
+		t.Errorf("death not logged: %v", h.m.game.Log)
+	}
+}
+
+func TestAttackRolls(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	troll := g.NewTroll(origin.Shift(1, 0))
+	g.ECS.RemoveComponent(troll, AI{})
+	kinds := map[hitKind]int{}
+	misses := 0
+	for range 1000 {
+		if !g.ECS.attack(0, troll, Damage{2, 4}, "") {
+			misses++
+			continue
+		}
+		dmgfx := GetComponent[DamageEffects](g.ECS, troll)
+		de := dmgfx.effects[len(dmgfx.effects)-1]
+		kinds[de.hit]++
+		lo, hi := 2, 4
+		switch de.hit {
+		case hitGraze:
+			lo, hi = 1, 2
+		case hitCrit:
+			lo, hi = 6, 8
+		}
+		if de.amount < lo || de.amount > hi {
+			t.Errorf("%v hit for %d, want %d-%d", de.hit, de.amount, lo, hi)
+		}
+	}
+	// With a 80% chance to hit, about 200 misses, 80 crits and 120 grazes.
+	if misses < 150 || misses > 250 || kinds[hitCrit] < 50 || kinds[hitGraze] < 80 {
+		t.Errorf("%d misses, %d crits, %d grazes", misses, kinds[hitCrit], kinds[hitGraze])
+	}
+	g.CollectMessages()
+	if !h.logged("You miss the troll.") {
+		t.Errorf("miss not logged")
+	}
+	// Evasion and accuracy shift the odds, within bounds.
+	g.ECS.AddComponent(troll, Evasion{200})
+	if got := g.ECS.hitChance(0, troll); got != MinHitChance {
+		t.Errorf("hit chance %d against an evasive troll, want %d", got, MinHitChance)
+	}
+	if got := g.ECS.hitChance(troll, 0); got != BaseHitChance-15 {
+		t.Errorf("troll hit chance %d, want %d", got, BaseHitChance-15)
+	}
+	// Armor takes off damage, but critical hits go through it.
+	g.ECS.AddComponents(0, Defense{3}, DamageEffects{effects: []DamageEffect{
+		{source: troll, amount: 5, attacker: "troll"},
+		{source: troll, amount: 5, hit: hitCrit, attacker: "troll"},
+	}})
+	g.ECS.DamageEffectSystem.Update(0)
+	g.CollectMessages()
+	if hp := GetComponent[Health](g.ECS, 0); hp.hp != hp.maxhp-2-5 {
+		t.Errorf("player hp %d, want %d", hp.hp, hp.maxhp-7)
+	}
+	if !h.logged("The troll mauls you! A critical hit!") {
+		t.Errorf("critical hit not logged: %v", g.Log)
+	}
+	// Attackers are named as they were when they attacked, even if they
+	// are gone by the time the damage is applied.
+	goblin := g.NewGoblin(origin.Shift(0, 1))
+	hit := false
+	for i := 0; i < 100 && !hit; i++ {
+		hit = g.ECS.attack(goblin, 0, Damage{1, 1}, "")
+	}
+	g.ECS.Delete(goblin)
+	g.ECS.DamageEffectSystem.Update(0)
+	g.CollectMessages()
+	if !h.logged("The goblin mauls you") {
+		t.Errorf("hit by a deleted goblin not logged: %v", g.Log)
+	}
+}
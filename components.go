@@ -67,8 +67,21 @@ type Health struct {
 	hp, maxhp int
 }
 
-// Entities with this component can damage entities with a health component.
+// Entities with this component can damage entities with a health component,
+// dealing between min and max damage per hit.
 type Damage struct {
+	min, max int
+}
+
+// Entities with this component are better (or worse) at hitting, by that many
+// percents.
+type Accuracy struct {
+	int
+}
+
+// Entities with this component are harder (or easier) to hit, by that many
+// percents.
+type Evasion struct {
 	int
 }
 
@@ -77,9 +90,10 @@ type Dead struct{}
 
 // Entities with this component perceive other entities around them.
 type Perception struct {
-	LOS       int     // Perceptive radius.
-	FOV       *rl.FOV // Effective FOV, which can be affected by occlusion.
-	perceived []int   // List of perceived entities.
+	LOS       int      // Perceptive radius.
+	FOV       *rl.FOV  // Effective FOV, which can be affected by occlusion.
+	perceived []Entity // List of perceived entities.
+	alertness int      // Chance, in percent, to notice a noise made next to it.
 }
 
 // Entities with this component are visible to those with Perception.
@@ -92,15 +106,26 @@ const (
 	CSWandering creatureState = "WANDERING"
 	CSSleeping  creatureState = "SLEEPING"
 	CSHunting   creatureState = "HUNTING"
+	CSSearching creatureState = "SEARCHING"
+	CSFleeing   creatureState = "FLEEING"
+	CSFollowing creatureState = "FOLLOWING"
 )
 
+// SearchTurns is the number of turns a monster spends looking around the
+// player's last known position before giving up.
+const SearchTurns = 5
+
 // Entities with this component will be controlled by AI, and can wander,
-// sleep, or hunt the player.
+// sleep, hunt the player, search for them after losing sight of them, flee
+// from them when badly wounded, or, for followers, follow them around.
 type AI struct {
 	state      creatureState
 	dest       *gruid.Point
 	cachedPath []gruid.Point // Last computed A* path; advanced one step per turn.
 	cachedDest *gruid.Point  // Destination used when cachedPath was computed.
+	target     Entity        // Entity being hunted, or fled from.
+	lastSeen   *gruid.Point  // Last known position of the target, while not yet reached.
+	search     int           // Turns left looking around, once lastSeen is reached.
 }
 
 // This component represents a message, to be processed by and added to the message log.
@@ -118,13 +143,41 @@ type Healing struct {
 	amount int
 }
 
+// Entities with this component put whoever they affect under the given
+// status, when drunk or when they shatter nearby.
+type Inflicts struct {
+	status statusID
+	nticks int
+	power  int
+}
+
+// Entities with this component break when thrown, spreading their effects
+// over their area of effect where they land. See ECS.Throw.
+type Shatters struct{}
+
 // Entities with this component can be picked up and placed in inventory.
 type Collectible struct{}
 
+// Entities with this component can be worn or wielded in the given slot.
+// Weapons add their Damage to the wielder's, armor and rings their Defense, and
+// light sources their LightSource.
+type Equippable struct {
+	slot Slot
+}
+
+// Items with this component are currently worn or wielded by the entity
+// carrying them.
+type Equipped struct{}
+
+// Entities with this component take that much less damage from each hit.
+type Defense struct {
+	int
+}
+
 // Entities with this component have an inventory, and can pick up Collectible
 // components.
 type Inventory struct {
-	items map[rune]int // maps assigned letter → entity ID
+	items map[rune]Entity // maps assigned letter → entity
 }
 
 // nextKey returns the first alphabetically available inventory letter.
@@ -138,7 +191,7 @@ func (inv Inventory) nextKey() rune {
 }
 
 // removeItem removes an entity from the inventory by its entity ID.
-func (inv Inventory) removeItem(entityID int) {
+func (inv Inventory) removeItem(entityID Entity) {
 	for k, v := range inv.items {
 		if v == entityID {
 			delete(inv.items, k)
@@ -147,14 +200,27 @@ func (inv Inventory) removeItem(entityID int) {
 	}
 }
 
+// removeStale drops references to items that have since been deleted from the
+// ECS, so that they are never mistaken for entities reusing their index.
+func (inv Inventory) removeStale(ecs *ECS) {
+	for k, v := range inv.items {
+		if !ecs.Alive(v) {
+			delete(inv.items, k)
+		}
+	}
+}
+
 // Entities with this component can be used for ranged attacks. e.g. staffs.
 type Ranged struct {
 	Range int
 }
 
-// Entities with this component will perform an action.
+// Entities with this component will perform an item action on their turn:
+// ActionPickup, or ActionUseItem or ActionDropItem on the item at key. See
+// ActionSystem.
 type Action struct {
 	action actionType
+	key    rune
 }
 
 // Entities with this component have an area of effect which is activated when
@@ -166,8 +232,12 @@ type AreaOfEffect struct {
 
 // Entities with this component will take damage.
 type DamageEffect struct {
-	source int
-	amount int
+	source   Entity
+	amount   int
+	missile  string  // What hit, for ranged attacks, e.g. "arrow".
+	hit      hitKind // How well the attack connected.
+	attacker string  // Name of the source when the attack was made.
+	weapon   string  // Weapon wielded by the source, for melee attacks.
 }
 
 type DamageEffects struct {
@@ -191,8 +261,124 @@ type Animation struct {
 	frames []Frame
 }
 
-type Confused struct {
-	nticks int
+// Entities with this component are under the given status effects. See
+// status.go.
+type Statuses struct {
+	effects map[statusID]status
+}
+
+// Entities with this component take turns. They gain speed energy per tick,
+// and act whenever they have at least EnergyThreshold, each action costing
+// some energy. See ECS.Update.
+type Energy struct {
+	amount int
+	speed  int
+}
+
+// Entities with this component lead to another level: the next one down if
+// down is true, and the previous one otherwise.
+type Stairs struct {
+	down bool
+}
+
+// Entities with this component belong to a faction, which determines who
+// they fight. See relations.
+type Faction struct {
+	faction
+}
+
+// Entities with this component follow the player, and obey their orders.
+// target is the monster they are told to attack, or the one the player is
+// fighting; 0 if none.
+type Follower struct {
+	order  Order
+	target Entity
+}
+
+// Entities with this component belong to the pack led by leader, which may be
+// themselves. Pack members stay close to their leader, and warn each other
+// when they spot an enemy.
+type Pack struct {
+	leader Entity
+}
+
+// Entities with this component pick what to do among the given goals. See
+// AISystem.Update.
+type Goals struct {
+	ids []goalID
+}
+
+// Entities with this component stay at post, only fighting adjacent enemies.
+type Guard struct {
+	post gruid.Point
+}
+
+// Entities with this component turn the monster they are used on into a
+// follower of the player.
+type Charming struct{}
+
+// Entities with this component fire a bolt when used: it flies toward where
+// it is aimed, and strikes the first creature in its way, or every creature
+// along its line if it pierces, with the item's Damage and Inflicts. Bolts fly
+// as glyph, or as a line pointing where they fly if glyph is 0. See ECS.zap.
+type Bolt struct {
+	missile string
+	glyph   rune
+	pierce  bool
+}
+
+// Entities with this component carry whoever uses them toward where they are
+// aimed, as far as the way is clear.
+type Blink struct{}
+
+// Entities with this component can only be used while they have charges left.
+// Staffs regain one every recharge ticks, while wands, with no recharge, run
+// out for good. See ChargeSystem.
+type Charges struct {
+	left, max int
+	recharge  int // Ticks needed to regain a charge, 0 if never.
+	timer     int // Ticks spent regaining the next charge.
+}
+
+// Entities with this component attack from up to rng tiles away, keeping
+// their distance from their target. Their missiles fly as glyph, or as a line
+// pointing where they fly if glyph is 0. See ShootSystem.
+type Shooter struct {
+	rng     int
+	missile string
+	glyph   rune
+	color   gruid.Color
+	status  statusID // Inflicted on hits, if any.
+}
+
+// Entities with this component shoot at the given position on their turn.
+type Shoot struct {
+	gruid.Point
+}
+
+// Entities with this component are missiles fired from from, flying along
+// path. They only last until the UI animates them (see collectAnimations).
+type Projectile struct {
+	from  gruid.Point
+	path  []gruid.Point
+	glyph rune
+	color gruid.Color
+}
+
+// Entities with this component are blasts spreading from center over area.
+// Like projectiles, they only last until the UI animates them.
+type Burst struct {
+	center gruid.Point
+	area   []gruid.Point
+	color  gruid.Color
+}
+
+// Entities with this component are noises, made at origin and heard up to
+// radius tiles away through walkable tiles. They only last until the next
+// tick, during which monsters may hear them (see PerceptionSystem.Hear).
+type Noise struct {
+	origin gruid.Point
+	radius int
 }
 
 // Entities with this component emit light.
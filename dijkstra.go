@@ -0,0 +1,161 @@
+// Dijkstra maps, as popularized by Brogue: for every tile of the map, the cost
+// of the cheapest path to the closest of a set of goals. Monsters consult them
+// by stepping to the neighbor with the lowest value (see Downhill), which is
+// much cheaper than computing a path per monster.
+
+package main
+
+import (
+	"container/heap"
+	"math"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+// Unreachable is the value of tiles from which no goal can be reached.
+const Unreachable = math.MaxInt
+
+// DijkstraGoal is a goal of a Dijkstra map, with the value it starts from.
+// Plain goals start from 0; lower values make a goal more attractive.
+type DijkstraGoal struct {
+	P    gruid.Point
+	Cost int
+}
+
+type DijkstraMap struct {
+	size  gruid.Point
+	dist  []int
+	queue dijkstraQueue
+}
+
+// NewDijkstraMap returns an empty Dijkstra map for a map of the given size.
+func NewDijkstraMap(size gruid.Point) *DijkstraMap {
+	dm := &DijkstraMap{size: size, dist: make([]int, size.X*size.Y)}
+	for i := range dm.dist {
+		dm.dist[i] = Unreachable
+	}
+	return dm
+}
+
+func (dm *DijkstraMap) idx(p gruid.Point) int {
+	return p.Y*dm.size.X + p.X
+}
+
+func (dm *DijkstraMap) point(i int) gruid.Point {
+	return gruid.Point{X: i % dm.size.X, Y: i / dm.size.X}
+}
+
+// At returns the value of the map at p. It is Unreachable out of the map.
+func (dm *DijkstraMap) At(p gruid.Point) int {
+	if !p.In(gruid.NewRange(0, 0, dm.size.X, dm.size.Y)) {
+		return Unreachable
+	}
+	return dm.dist[dm.idx(p)]
+}
+
+// Compute fills the map with the cost of reaching the goals from every tile,
+// moving through walkable tiles of m. As for AI paths, diagonal moves cost 14
+// and cardinal ones 10.
+func (dm *DijkstraMap) Compute(m *Map, goals []DijkstraGoal) {
+	for i := range dm.dist {
+		dm.dist[i] = Unreachable
+	}
+	dm.queue = dm.queue[:0]
+	for _, g := range goals {
+		if i := dm.idx(g.P); g.Cost < dm.dist[i] {
+			dm.dist[i] = g.Cost
+			dm.queue = append(dm.queue, dijkstraNode{i, g.Cost})
+		}
+	}
+	heap.Init(&dm.queue)
+	for dm.queue.Len() > 0 {
+		n := heap.Pop(&dm.queue).(dijkstraNode)
+		if n.cost > dm.dist[n.idx] {
+			continue // Outdated entry.
+		}
+		p := dm.point(n.idx)
+		for _, d := range Directions {
+			q := p.Add(d)
+			if !m.Walkable(q) {
+				continue
+			}
+			cost := n.cost + 10
+			if d.X != 0 && d.Y != 0 {
+				cost = n.cost + 14
+			}
+			if j := dm.idx(q); cost < dm.dist[j] {
+				dm.dist[j] = cost
+				heap.Push(&dm.queue, dijkstraNode{j, cost})
+			}
+		}
+	}
+}
+
+// Downhill returns the neighbor of p with the lowest value, among those for
+// which free returns true. It returns false if none is lower than p itself.
+func (dm *DijkstraMap) Downhill(p gruid.Point, free func(gruid.Point) bool) (gruid.Point, bool) {
+	best, bestv := p, dm.At(p)
+	for _, d := range Directions {
+		q := p.Add(d)
+		if v := dm.At(q); v < bestv && free(q) {
+			best, bestv = q, v
+		}
+	}
+	return best, best != p
+}
+
+// Max returns the highest reachable value of the map, or Unreachable if
+// there is none.
+func (dm *DijkstraMap) Max() int {
+	v := Unreachable
+	for _, d := range dm.dist {
+		if d != Unreachable && (v == Unreachable || d > v) {
+			v = d
+		}
+	}
+	return v
+}
+
+type dijkstraNode struct {
+	idx, cost int
+}
+
+// dijkstraQueue implements heap.Interface.
+type dijkstraQueue []dijkstraNode
+
+func (q dijkstraQueue) Len() int           { return len(q) }
+func (q dijkstraQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q dijkstraQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *dijkstraQueue) Push(x any)        { *q = append(*q, x.(dijkstraNode)) }
+
+func (q *dijkstraQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// UpdateDijkstraMaps recomputes the maps monsters consult: the distance to
+// the player, the safety map derived from it, and the distance to tiles the
+// player has not explored yet.
+func (m *Map) UpdateDijkstraMaps(player gruid.Point) {
+	m.PlayerMap.Compute(m, []DijkstraGoal{{P: player}})
+	// Brogue's trick: scaling the distance to the player by a negative
+	// factor and relaxing the result gives a map whose slopes lead away from
+	// the player, but around them rather than into dead ends.
+	goals := []DijkstraGoal{}
+	for i, d := range m.PlayerMap.dist {
+		if d != Unreachable {
+			goals = append(goals, DijkstraGoal{P: m.PlayerMap.point(i), Cost: -d * 12 / 10})
+		}
+	}
+	m.SafetyMap.Compute(m, goals)
+	goals = goals[:0]
+	it := m.Grid.Iterator()
+	for it.Next() {
+		if it.Cell() == Floor && !m.Explored[m.idx(it.P())] {
+			goals = append(goals, DijkstraGoal{P: it.P()})
+		}
+	}
+	m.UnexploredMap.Compute(m, goals)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+func TestDijkstraMap(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	g.Map.UpdateDijkstraMaps(origin)
+	if v := g.Map.PlayerMap.At(origin.Shift(2, 1)); v != 24 {
+		t.Errorf("player map at knight move: %d, want 24", v)
+	}
+	if v := g.Map.PlayerMap.At(gruid.Point{}); v != Unreachable {
+		t.Errorf("player map in wall: %d, want unreachable", v)
+	}
+	// Fleeing from the player leads away from them.
+	p := origin.Shift(3, 0)
+	q, ok := g.Map.SafetyMap.Downhill(p, func(gruid.Point) bool { return true })
+	if !ok || g.Map.PlayerMap.At(q) <= g.Map.PlayerMap.At(p) {
+		t.Errorf("safety map leads from %v to %v", p, q)
+	}
+	// Tiles out of the player's sight are not explored yet.
+	if v := g.Map.UnexploredMap.At(origin); v == 0 {
+		t.Errorf("player position unexplored")
+	}
+}
@@ -8,43 +8,59 @@ import (
 )
 
 type ECS struct {
-	entities   []int
-	nextID     int
-	Map        *Map
-	components map[int]map[string]Component
+	entities []Entity     // Live entities, in creation order.
+	slots    []entitySlot // Generation and liveness of each entity index.
+	free     []uint32     // Indices of deleted entities, ready for reuse.
+	Map      *Map
+	stores   []componentStore // Component stores, indexed by componentID.
+	spatial  *spatialIndex    // Entities by tile, maintained from Position.
+	Turn     int              // Number of turns played so far.
+	noise    *DijkstraMap     // Scratch map for propagating noises.
 	PerceptionSystem
 	AISystem
 	BumpSystem
+	ShootSystem
+	ActionSystem
 	FOVSystem
 	DeathSystem
 	DamageEffectSystem
 	DebugSystem
 	AnimationSystem
-	ConfusedSystem
+	StatusSystem
+	ChargeSystem
 	LightingSystem
+	EnergySystem
 }
 
 // Note that we do not initialize the map here. The idea is that
 // the callee is initializing that and will assign it right after this.
 func NewECS() *ECS {
 	ecs := &ECS{
-		nextID:     0,
-		components: make(map[int]map[string]Component),
+		stores:  newComponentStores(),
+		spatial: newSpatialIndex(),
 	}
+	positions := storeFor[Position](ecs)
+	positions.onSet = ecs.spatial.onSet
+	positions.onRemove = ecs.spatial.onRemove
 	ecs.PerceptionSystem = PerceptionSystem{ecs: ecs}
 	ecs.AISystem = AISystem{ecs: ecs, aip: &aiPath{ecs: ecs}}
 	ecs.BumpSystem = BumpSystem{ecs: ecs}
+	ecs.ShootSystem = ShootSystem{ecs: ecs}
+	ecs.ActionSystem = ActionSystem{ecs: ecs}
 	ecs.FOVSystem = FOVSystem{ecs: ecs}
 	ecs.DeathSystem = DeathSystem{ecs: ecs}
 	ecs.DamageEffectSystem = DamageEffectSystem{ecs: ecs}
 	ecs.AnimationSystem = AnimationSystem{ecs: ecs}
 	ecs.DebugSystem = DebugSystem{ecs: ecs}
-	ecs.ConfusedSystem = ConfusedSystem{ecs: ecs}
+	ecs.StatusSystem = StatusSystem{ecs: ecs}
+	ecs.ChargeSystem = ChargeSystem{ecs: ecs}
 	ecs.LightingSystem = LightingSystem{ecs: ecs}
+	ecs.EnergySystem = EnergySystem{ecs: ecs}
 	return ecs
 }
 
 func (ecs *ECS) Initialize() {
+	ecs.updateDijkstraMaps()
 	for _, e := range ecs.entities {
 		ecs.PerceptionSystem.Update(e)
 		ecs.AISystem.Update(e)
@@ -53,40 +69,160 @@ func (ecs *ECS) Initialize() {
 	ecs.LightingSystem.UpdateLighting()
 }
 
-// Iterates through each entity
+// Update resolves the player's pending action, then lets time pass, one tick
+// at a time, until the player has enough energy to act again. Other entities
+// act during those ticks whenever their own energy allows, so that faster
+// entities act more often. Actions that cost the player no energy take no
+// time at all. Once the player is dead, each update plays exactly one tick.
 func (ecs *ECS) Update() {
-	for _, e := range ecs.entities {
-		ecs.PerceptionSystem.Update(e)
-		ecs.AISystem.Update(e)
-		ecs.ConfusedSystem.Update(e)
-		ecs.BumpSystem.Update(e)
-		ecs.FOVSystem.Update(e)
+	ecs.act(0)
+	ecs.resolve()
+	ecs.updateDijkstraMaps()
+	if !ecs.HasComponent(0, Energy{}) {
+		ecs.tick()
 	}
-	for _, e := range ecs.entities {
-		ecs.DamageEffectSystem.Update(e)
-		ecs.DeathSystem.Update(e)
+	for ecs.HasComponent(0, Energy{}) && !ecs.Ready(0) {
+		ecs.tick()
 	}
 	ecs.LightingSystem.UpdateLighting()
 	// ecs.DebugSystem.Update()
 }
 
+// tick advances the game by one unit of time: entities gain energy, and
+// every entity other than the player acts as long as it has enough.
+func (ecs *ECS) tick() {
+	ecs.Turn++
+	actors := ecs.EntitiesWith(Energy{})
+	for _, e := range actors {
+		ecs.EnergySystem.Update(e)
+	}
+	for _, e := range ecs.EntitiesWith(Statuses{}) {
+		ecs.StatusSystem.Update(e)
+	}
+	for _, e := range ecs.EntitiesWith(Charges{}) {
+		ecs.ChargeSystem.Update(e)
+	}
+	ecs.listen()
+	for _, e := range actors {
+		if e == 0 {
+			continue
+		}
+		for ecs.Ready(e) {
+			ecs.act(e)
+		}
+	}
+	ecs.resolve()
+}
+
+// MakeNoise makes a noise at p, to be heard during the next tick. Only the
+// player's actions make noise, so that mobs hearing one go look for the
+// player: callers check that the actor is the player.
+func (ecs *ECS) MakeNoise(p gruid.Point, radius int) {
+	ecs.Create(Noise{origin: p, radius: radius})
+}
+
+// listen lets mobs hear the noises made since the last tick, and then
+// discards them. Mobs listen whether they are about to act or not.
+func (ecs *ECS) listen() {
+	noises := ecs.EntitiesWith(Noise{})
+	if len(noises) == 0 {
+		return
+	}
+	if ecs.noise == nil {
+		ecs.noise = NewDijkstraMap(ecs.Map.Grid.Size())
+	}
+	for _, ne := range noises {
+		n := GetComponent[Noise](ecs, ne)
+		ecs.noise.Compute(ecs.Map, []DijkstraGoal{{P: n.origin}})
+		for _, e := range ecs.EntitiesWith(AI{}) {
+			ecs.PerceptionSystem.Hear(e, n, ecs.noise)
+		}
+		ecs.Delete(ne)
+	}
+}
+
+// act lets an entity take one action. Entities that end up doing nothing
+// still spend the cost of waiting.
+func (ecs *ECS) act(e Entity) {
+	before, _ := Get[Energy](ecs, e)
+	ecs.PerceptionSystem.Update(e)
+	ecs.AISystem.Update(e)
+	ecs.StatusSystem.Act(e)
+	ecs.BumpSystem.Update(e)
+	ecs.ShootSystem.Update(e)
+	ecs.ActionSystem.Update(e)
+	ecs.FOVSystem.Update(e)
+	if after, _ := Get[Energy](ecs, e); e != 0 && after.amount == before.amount {
+		ecs.Spend(e, CostWait)
+	}
+}
+
+// resolve applies the damage dealt so far, and handles deaths.
+func (ecs *ECS) resolve() {
+	for _, e := range ecs.EntitiesWith() {
+		ecs.DamageEffectSystem.Update(e)
+		ecs.DeathSystem.Update(e)
+	}
+}
+
+// updateDijkstraMaps recomputes the map's Dijkstra maps from the player's
+// position. The player does not move while other entities act, so once per
+// update is enough.
+func (ecs *ECS) updateDijkstraMaps() {
+	if pos, ok := Get[Position](ecs, 0); ok {
+		ecs.Map.UpdateDijkstraMaps(pos.Point)
+	}
+}
+
+// wounded returns true if the entity has lost half of its health or more.
+// Wounded monsters flee.
+func (ecs *ECS) wounded(e Entity) bool {
+	h, ok := Get[Health](ecs, e)
+	return ok && h.hp*2 <= h.maxhp
+}
+
+// Ready returns true if the entity has enough energy to act.
+func (ecs *ECS) Ready(e Entity) bool {
+	en, ok := Get[Energy](ecs, e)
+	return ok && en.amount >= EnergyThreshold
+}
+
+// Spend removes the cost of an action from the entity's energy. Entities
+// without energy act for free.
+func (ecs *ECS) Spend(e Entity, cost int) {
+	if en, ok := Get[Energy](ecs, e); ok {
+		en.amount -= cost
+		ecs.AddComponent(e, en)
+	}
+}
+
 func (ecs *ECS) UpdateAnimation() {
 	for _, e := range ecs.EntitiesWith(Animation{}) {
 		ecs.AnimationSystem.Update(e)
 	}
 }
 
-func (ecs *ECS) Create(components ...any) int {
-	idx := ecs.nextID
-	ecs.entities = append(ecs.entities, idx)
+// Create adds a new entity with the given components. The index of a deleted
+// entity is reused if there is one, under a new generation.
+func (ecs *ECS) Create(components ...any) Entity {
+	var e Entity
+	if n := len(ecs.free); n > 0 {
+		idx := ecs.free[n-1]
+		ecs.free = ecs.free[:n-1]
+		ecs.slots[idx].alive = true
+		e = newEntity(idx, ecs.slots[idx].gen)
+	} else {
+		ecs.slots = append(ecs.slots, entitySlot{alive: true})
+		e = newEntity(uint32(len(ecs.slots)-1), 0)
+	}
+	ecs.entities = append(ecs.entities, e)
 	for _, component := range components {
-		ecs.AddComponent(idx, component)
+		ecs.AddComponent(e, component)
 	}
-	ecs.nextID++
-	return idx
+	return e
 }
 
-func remove(slice []int, s int) []int {
+func remove(slice []Entity, s Entity) []Entity {
 	idx := -1
 	for i := 0; i < len(slice); i++ {
 		if slice[i] == s {
@@ -100,90 +236,113 @@ func remove(slice []int, s int) []int {
 	return slice
 }
 
-func removeAt(slice []int, idx int) []int {
+func removeAt(slice []Entity, idx int) []Entity {
 	return append(slice[:idx], slice[idx+1:]...)
 }
 
-func (ecs *ECS) Delete(entity int) {
+// Delete removes an entity and all of its components. Its index is recycled
+// under a new generation, so that handles to it are no longer Alive.
+func (ecs *ECS) Delete(entity Entity) {
+	if !ecs.Alive(entity) {
+		return
+	}
 	// Remove from entity list
 	ecs.entities = remove(ecs.entities, entity)
-	delete(ecs.components, entity)
+	ecs.ClearAllComponents(entity)
+	slot := &ecs.slots[entity.Index()]
+	slot.alive = false
+	slot.gen++
+	ecs.free = append(ecs.free, uint32(entity.Index()))
 }
 
-func (ecs *ECS) Exists(entity int) bool {
-	for _, e := range ecs.entities {
-		if entity == e {
-			return true
-		}
+// Alive returns true if the handle refers to an entity that has not been
+// deleted.
+func (ecs *ECS) Alive(entity Entity) bool {
+	idx := entity.Index()
+	if idx >= len(ecs.slots) {
+		return false
 	}
-	return false
+	slot := ecs.slots[idx]
+	return slot.alive && slot.gen == entity.Gen()
+}
+
+func (ecs *ECS) Exists(entity Entity) bool {
+	return ecs.Alive(entity)
 }
 
 // Adds a component to an entity. If one of this type already exists,
-// replaces it.
-func (ecs *ECS) AddComponent(entity int, component any) {
-	if componentMap, ok := ecs.components[entity]; ok {
-		componentString := fmt.Sprintf("%T", component)
-		componentMap[componentString] = component
-	} else {
-		ecs.components[entity] = make(map[string]Component)
-		ecs.AddComponent(entity, component)
+// replaces it. Stale handles, whose entity has been deleted, are refused, as
+// are handles of entities that never existed: nothing is added.
+func (ecs *ECS) AddComponent(entity Entity, component any) {
+	if !ecs.Alive(entity) {
+		return
 	}
+	ecs.stores[componentIDOf(component)].setAny(entity, component)
 }
 
-func (ecs *ECS) AddComponents(entity int, components ...any) {
+func (ecs *ECS) AddComponents(entity Entity, components ...any) {
 	for _, c := range components {
 		ecs.AddComponent(entity, c)
 	}
 }
 
-func (ecs *ECS) GetComponent(entity int, component Component) (Component, bool) {
-	if _, ok := ecs.components[entity]; ok {
-		componentString := fmt.Sprintf("%T", component)
-		if component, exists := ecs.components[entity][componentString]; exists {
-			return component, true
-		} else {
-			return nil, false
-		}
-	}
-	return nil, false
+func (ecs *ECS) GetComponent(entity Entity, component Component) (Component, bool) {
+	return ecs.stores[componentIDOf(component)].getAny(entity)
 }
 
-func (ecs *ECS) GetComponentUnchecked(entity int, component Component) Component {
-	componentString := fmt.Sprintf("%T", component)
-	return ecs.components[entity][componentString]
+func (ecs *ECS) GetComponentUnchecked(entity Entity, component Component) Component {
+	c, _ := ecs.GetComponent(entity, component)
+	return c
 }
 
-func GetComponent[T Component](ecs *ECS, entity int) T {
-	var zero T
-	return ecs.GetComponentUnchecked(entity, zero).(T)
+// GetComponent returns the T component of an entity. It panics if the entity
+// does not have one; use Get when the component may be missing.
+func GetComponent[T Component](ecs *ECS, entity Entity) T {
+	c, ok := Get[T](ecs, entity)
+	if !ok {
+		panic(fmt.Sprintf("entity %v has no %T component", entity, c))
+	}
+	return c
 }
 
-func (ecs *ECS) GetComponentsFor(entity int) map[string]Component {
-	return ecs.components[entity]
+// GetComponentsFor returns every component of an entity, keyed by type name.
+// It is meant for debugging output only.
+func (ecs *ECS) GetComponentsFor(entity Entity) map[string]Component {
+	comps := map[string]Component{}
+	for _, s := range ecs.stores {
+		if c, ok := s.getAny(entity); ok {
+			comps[s.typeName()] = c
+		}
+	}
+	return comps
 }
 
-func (ecs *ECS) RemoveComponent(entity int, component Component) {
-	if _, ok := ecs.components[entity]; ok {
-		componentString := fmt.Sprintf("%T", component)
-		delete(ecs.components[entity], componentString)
+// moveComponents moves every component of an entity over to the entity de of
+// another ECS, leaving it without any component.
+func (ecs *ECS) moveComponents(e Entity, dst *ECS, de Entity) {
+	for _, s := range ecs.stores {
+		if c, ok := s.getAny(e); ok {
+			dst.AddComponent(de, c)
+		}
 	}
+	ecs.ClearAllComponents(e)
 }
 
-func (ecs *ECS) ClearAllComponents(entity int) {
-	if _, ok := ecs.components[entity]; ok {
-		ecs.components[entity] = make(map[string]Component)
-	}
+func (ecs *ECS) RemoveComponent(entity Entity, component Component) {
+	ecs.stores[componentIDOf(component)].remove(entity)
 }
 
-func (ecs *ECS) HasComponent(entity int, component any) bool {
-	if _, ok := ecs.GetComponent(entity, component); ok {
-		return true
+func (ecs *ECS) ClearAllComponents(entity Entity) {
+	for _, s := range ecs.stores {
+		s.remove(entity)
 	}
-	return false
 }
 
-func (ecs *ECS) HasComponents(entity int, components ...any) bool {
+func (ecs *ECS) HasComponent(entity Entity, component any) bool {
+	return ecs.stores[componentIDOf(component)].has(entity)
+}
+
+func (ecs *ECS) HasComponents(entity Entity, components ...any) bool {
 	for _, c := range components {
 		if !ecs.HasComponent(entity, c) {
 			return false
@@ -192,26 +351,39 @@ func (ecs *ECS) HasComponents(entity int, components ...any) bool {
 	return true
 }
 
-func (ecs *ECS) EntitiesWith(components ...any) (entities []int) {
-	for _, e := range ecs.entities {
-		if ecs.HasComponents(e, components...) {
-			entities = append(entities, e)
+// EntitiesWith returns the entities holding all of the given components. Only
+// the smallest of the corresponding stores is scanned.
+func (ecs *ECS) EntitiesWith(components ...any) (entities []Entity) {
+	if len(components) == 0 {
+		return append(entities, ecs.entities...)
+	}
+	ids := make([]componentID, len(components))
+	smallest := 0
+	for i, c := range components {
+		ids[i] = componentIDOf(c)
+		if len(ecs.stores[ids[i]].entities()) < len(ecs.stores[ids[smallest]].entities()) {
+			smallest = i
 		}
 	}
-	return entities
-}
-
-func (ecs *ECS) EntitiesAt(p gruid.Point) (entities []int) {
-	for _, e := range ecs.EntitiesWith(Position{}) {
-		if p == GetComponent[Position](ecs, e).Point {
-			entities = append(entities, e)
+outer:
+	for _, e := range ecs.stores[ids[smallest]].entities() {
+		for _, id := range ids {
+			if !ecs.stores[id].has(e) {
+				continue outer
+			}
 		}
+		entities = append(entities, e)
 	}
 	return entities
 }
 
-func (ecs *ECS) EntitiesAtPWith(p gruid.Point, components ...any) (entities []int) {
-	for _, e := range ecs.EntitiesAt(p) {
+// EntitiesAt returns the entities positioned at p, using the spatial index.
+func (ecs *ECS) EntitiesAt(p gruid.Point) (entities []Entity) {
+	return append(entities, ecs.spatial.at(p)...)
+}
+
+func (ecs *ECS) EntitiesAtPWith(p gruid.Point, components ...any) (entities []Entity) {
+	for _, e := range ecs.spatial.at(p) {
 		if ecs.HasComponents(e, components...) {
 			entities = append(entities, e)
 		}
@@ -221,7 +393,12 @@ func (ecs *ECS) EntitiesAtPWith(p gruid.Point, components ...any) (entities []in
 
 // Returns true if there is no blocking entity at p.
 func (ecs *ECS) NoBlockingEntityAt(p gruid.Point) bool {
-	return len(ecs.EntitiesAtPWith(p, ObstructsMovement{})) == 0
+	for _, e := range ecs.spatial.at(p) {
+		if Has[ObstructsMovement](ecs, e) {
+			return false
+		}
+	}
+	return true
 }
 
 func (ecs *ECS) BloodAt(p gruid.Point) bool {
@@ -245,8 +422,8 @@ func (ecs *ECS) PlayerDead() bool {
 	return false
 }
 
-func (ecs *ECS) printDebug(e int, showFOV bool) {
-	fmt.Printf("Entity: %d\n", e)
+func (ecs *ECS) printDebug(e Entity, showFOV bool) {
+	fmt.Printf("Entity: %v\n", e)
 	comps := ecs.GetComponentsFor(e)
 	comps_copy := make(map[string]Component, len(comps))
 	for k, v := range comps {
@@ -7,7 +7,7 @@ import (
 	"codeberg.org/anaseto/gruid"
 )
 
-// func (g *game) NewBaseCreature(p gruid.Point) int {
+// func (g *game) NewBaseCreature(p gruid.Point) Entity {
 // 	return g.ECS.Create(
 // 		Position{p},
 // 		Visible{},
@@ -25,40 +25,123 @@ func NewRenderableNoBg(Rune rune, fg gruid.Color, order renderOrder) Renderable
 	return Renderable{cell: gruid.Cell{Rune: Rune, Style: gruid.Style{Fg: fg, Bg: ColorNone}}, order: order}
 }
 
-func (g *game) NewPlayer(p gruid.Point) int {
-	return g.ECS.Create(
+// NewPlayer turns entity 0, which is reserved for the player on every level
+// (see newLevel), into the player. The player starts with a sword in hand.
+func (g *game) NewPlayer(p gruid.Point) Entity {
+	g.ECS.AddComponents(0,
 		Name{"you"},
 		Position{p},
 		Visible{},
 		NewRenderableNoBg('@', ColorPlayer, ROActor),
 		Health{hp: 18, maxhp: 18},
-		Damage{5},
+		Damage{1, 2},
 		DamageEffects{effects: []DamageEffect{}}, // Initialize with an empty list of effects
 		FOV{LOS: 20},
 		Perception{LOS: 20},
-		Inventory{items: map[rune]int{}},
+		Inventory{items: map[rune]Entity{}},
 		Input{},
 		ObstructsMovement{},
 		LightSource{Radius: 10, Intensity: 1.0},
+		Energy{amount: EnergyThreshold, speed: NormalSpeed},
+		Faction{FactionPlayer},
 	)
+	g.equipPlayer(g.NewSword(p))
+	return 0
+}
+
+// equipPlayer puts the item it in the player's inventory, and wields or wears
+// it.
+func (g *game) equipPlayer(it Entity) {
+	inv := g.PlayerInventory()
+	g.ECS.RemoveComponent(it, Position{})
+	g.ECS.AddComponent(it, Equipped{})
+	inv.items[inv.nextKey()] = it
+	g.ECS.AddComponent(0, inv)
 }
 
-func (g *game) NewGoblin(p gruid.Point) int {
+func (g *game) NewGoblin(p gruid.Point) Entity {
 	return g.ECS.Create(
 		Name{"goblin"},
 		Position{p},
 		Visible{},
 		NewRenderableNoBg('g', ColorMonster, ROActor),
 		Health{hp: 10, maxhp: 10},
-		Damage{2},
+		Damage{1, 3},
 		DamageEffects{effects: []DamageEffect{}}, // Initialize with an empty list of effects
-		Perception{LOS: 8},
+		Perception{LOS: 8, alertness: 60},
 		AI{state: CSWandering},
+		Goals{goblinGoals},
 		ObstructsMovement{},
+		Energy{speed: NormalSpeed},
+		Faction{FactionGoblin},
+		Inventory{items: map[rune]Entity{}},
 	)
 }
 
-func (g *game) NewTroll(p gruid.Point) int {
+// NewGoblinChief creates the leader of a goblin war party.
+func (g *game) NewGoblinChief(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"goblin chief"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('G', ColorMonster, ROActor),
+		Health{hp: 15, maxhp: 15},
+		Damage{2, 4},
+		DamageEffects{effects: []DamageEffect{}}, // Initialize with an empty list of effects
+		Perception{LOS: 8, alertness: 70},
+		AI{state: CSWandering},
+		Goals{goblinGoals},
+		ObstructsMovement{},
+		Energy{speed: NormalSpeed},
+		Faction{FactionGoblin},
+		Inventory{items: map[rune]Entity{}},
+	)
+}
+
+// NewGoblinArcher creates a goblin that shoots arrows from afar.
+func (g *game) NewGoblinArcher(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"goblin archer"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('g', ColorArrow, ROActor),
+		Health{hp: 8, maxhp: 8},
+		Damage{1, 3},
+		DamageEffects{effects: []DamageEffect{}}, // Initialize with an empty list of effects
+		Perception{LOS: 8, alertness: 60},
+		Accuracy{10}, // Archers are good shots.
+		AI{state: CSWandering},
+		Goals{shooterGoals},
+		ObstructsMovement{},
+		Energy{speed: NormalSpeed},
+		Faction{FactionGoblin},
+		Inventory{items: map[rune]Entity{}},
+		Shooter{rng: 7, missile: "arrow", color: ColorArrow},
+	)
+}
+
+// NewGoblinShaman creates a goblin that hurls firebolts.
+func (g *game) NewGoblinShaman(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"goblin shaman"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('g', ColorFirebolt, ROActor),
+		Health{hp: 8, maxhp: 8},
+		Damage{2, 6},
+		DamageEffects{effects: []DamageEffect{}}, // Initialize with an empty list of effects
+		Perception{LOS: 8, alertness: 50},
+		AI{state: CSWandering},
+		Goals{shooterGoals},
+		ObstructsMovement{},
+		Energy{speed: 80},
+		Faction{FactionGoblin},
+		Inventory{items: map[rune]Entity{}},
+		Shooter{rng: 6, missile: "firebolt", glyph: '*', color: ColorFirebolt, status: StatusBurning},
+	)
+}
+
+func (g *game) NewTroll(p gruid.Point) Entity {
 	return g.ECS.Create(
 		Name{"troll"},
 		Position{p},
@@ -66,14 +149,18 @@ func (g *game) NewTroll(p gruid.Point) int {
 		NewRenderableNoBg('T', ColorTroll, ROActor),
 		Health{hp: 20, maxhp: 20},
 		DamageEffects{effects: []DamageEffect{}}, // Initialize with an empty list of effects
-		Damage{5},
-		Perception{LOS: 6},
+		Damage{3, 7},
+		Perception{LOS: 6, alertness: 30},
+		Accuracy{-15}, // But clumsy.
 		AI{state: CSWandering},
+		Goals{defaultGoals},
 		ObstructsMovement{},
+		Energy{speed: 80}, // Trolls are slow.
+		Faction{FactionTroll},
 	)
 }
 
-func (g *game) NewHealthPotion(p gruid.Point) int {
+func (g *game) NewHealthPotion(p gruid.Point) Entity {
 	return g.ECS.Create(
 		Name{"health potion"},
 		Position{p},
@@ -82,10 +169,124 @@ func (g *game) NewHealthPotion(p gruid.Point) int {
 		Collectible{},
 		Consumable{},
 		Healing{amount: 5},
+		Shatters{},
+		AreaOfEffect{radius: 1},
+	)
+}
+
+func (g *game) NewPotionOfFire(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"potion of fire"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('¡', ColorFirebolt, ROItem),
+		Collectible{},
+		Consumable{},
+		Inflicts{status: StatusBurning, nticks: 4, power: 2},
+		Shatters{},
+		AreaOfEffect{radius: 1},
+	)
+}
+
+func (g *game) NewPotionOfConfusion(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"potion of confusion"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('¡', ColorLogSpecial, ROItem),
+		Collectible{},
+		Consumable{},
+		Inflicts{status: StatusConfused, nticks: 8},
+		Shatters{},
+		AreaOfEffect{radius: 2},
+	)
+}
+
+func (g *game) NewPotionOfPoison(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"potion of poison"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('¡', ColorGrass, ROItem),
+		Collectible{},
+		Consumable{},
+		Inflicts{status: StatusPoisoned, nticks: 6, power: 1},
+		Shatters{},
+		AreaOfEffect{radius: 1},
+	)
+}
+
+func (g *game) NewPotionOfParalysis(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"potion of paralysis"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('¡', ColorStatusWounded, ROItem),
+		Collectible{},
+		Consumable{},
+		Inflicts{status: StatusParalyzed, nticks: 4},
+		Shatters{},
+		AreaOfEffect{radius: 1},
 	)
 }
 
-func (g *game) NewCorpse(p gruid.Point) int {
+func (g *game) NewPotionOfRegeneration(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"potion of regeneration"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('¡', ColorStatusHealthy, ROItem),
+		Collectible{},
+		Consumable{},
+		Inflicts{status: StatusRegenerating, nticks: 10, power: 1},
+		Shatters{},
+		AreaOfEffect{radius: 1},
+	)
+}
+
+func (g *game) NewPotionOfBlindness(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"potion of blindness"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('¡', ColorCorpse, ROItem),
+		Collectible{},
+		Consumable{},
+		Inflicts{status: StatusBlind, nticks: 10},
+		Shatters{},
+		AreaOfEffect{radius: 1},
+	)
+}
+
+func (g *game) NewPotionOfLevitation(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"potion of levitation"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('¡', ColorPlayer, ROItem),
+		Collectible{},
+		Consumable{},
+		Inflicts{status: StatusLevitating, nticks: 20},
+		Shatters{},
+		AreaOfEffect{radius: 1},
+	)
+}
+
+func (g *game) NewPotionOfHaste(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"potion of haste"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('¡', ColorAlly, ROItem),
+		Collectible{},
+		Consumable{},
+		Inflicts{status: StatusHasted, nticks: 15},
+		Shatters{},
+		AreaOfEffect{radius: 1},
+	)
+}
+
+func (g *game) NewCorpse(p gruid.Point) Entity {
 	return g.ECS.Create(
 		Name{"corpse"},
 		Position{p},
@@ -94,10 +295,11 @@ func (g *game) NewCorpse(p gruid.Point) int {
 		Collectible{},
 		Consumable{},
 		Healing{amount: 2},
+		Dead{},
 	)
 }
 
-func (g *game) NewBlood(p gruid.Point) int {
+func (g *game) NewBlood(p gruid.Point) Entity {
 	return g.ECS.Create(
 		Name{"pool of blood"},
 		Visible{},
@@ -106,7 +308,7 @@ func (g *game) NewBlood(p gruid.Point) int {
 	)
 }
 
-func (g *game) NewScroll(p gruid.Point) int {
+func (g *game) NewScroll(p gruid.Point) Entity {
 	return g.ECS.Create(
 		Name{"scroll"},
 		Position{p},
@@ -115,12 +317,195 @@ func (g *game) NewScroll(p gruid.Point) int {
 		Collectible{},
 		Consumable{},
 		Ranged{Range: 6},
-		Damage{5},
+		Damage{4, 6},
 		AreaOfEffect{radius: 3},
 	)
 }
 
-func (g *game) NewTorch(p gruid.Point) int {
+func (g *game) NewScrollOfCharming(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"scroll of charming"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('?', ColorAlly, ROItem),
+		Collectible{},
+		Consumable{},
+		Ranged{Range: 6},
+		Charming{},
+	)
+}
+
+func (g *game) NewStaffOfFirebolt(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"staff of firebolt"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('/', ColorFirebolt, ROItem),
+		Collectible{},
+		Ranged{Range: 6},
+		Bolt{missile: "firebolt", glyph: '*'},
+		Damage{2, 5},
+		Inflicts{status: StatusBurning, nticks: ShotStatusTicks, power: 1},
+		Charges{left: 3, max: 3, recharge: 40},
+	)
+}
+
+func (g *game) NewStaffOfBlinking(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"staff of blinking"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('/', ColorLogSpecial, ROItem),
+		Collectible{},
+		Ranged{Range: 5},
+		Blink{},
+		Charges{left: 2, max: 2, recharge: 60},
+	)
+}
+
+func (g *game) NewWandOfLightning(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"wand of lightning"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('-', ColorLightning, ROItem),
+		Collectible{},
+		Ranged{Range: 8},
+		Bolt{missile: "lightning bolt", pierce: true},
+		Damage{3, 8},
+		Charges{left: 4, max: 4},
+	)
+}
+
+func (g *game) NewWandOfSlowness(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"wand of slowness"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('-', ColorWater1, ROItem),
+		Collectible{},
+		Ranged{Range: 6},
+		Bolt{missile: "bolt of slowness", glyph: '*'},
+		Inflicts{status: StatusSlowed, nticks: 10},
+		Charges{left: 5, max: 5},
+	)
+}
+
+func (g *game) NewWandOfEntrancement(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"wand of entrancement"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('-', ColorAlly, ROItem),
+		Collectible{},
+		Ranged{Range: 6},
+		Bolt{missile: "bolt of entrancement", glyph: '*'},
+		Inflicts{status: StatusEntranced, nticks: 8},
+		Charges{left: 3, max: 3},
+	)
+}
+
+func (g *game) NewDagger(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"dagger"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg(')', ColorEquipment, ROItem),
+		Collectible{},
+		Equippable{SlotWeapon},
+		Damage{1, 2},
+	)
+}
+
+func (g *game) NewSword(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"sword"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg(')', ColorEquipment, ROItem),
+		Collectible{},
+		Equippable{SlotWeapon},
+		Damage{2, 5},
+	)
+}
+
+func (g *game) NewLeatherArmor(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"leather armor"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('[', ColorEquipment, ROItem),
+		Collectible{},
+		Equippable{SlotArmor},
+		Defense{1},
+	)
+}
+
+func (g *game) NewChainMail(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"chain mail"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('[', ColorEquipment, ROItem),
+		Collectible{},
+		Equippable{SlotArmor},
+		Defense{2},
+	)
+}
+
+func (g *game) NewRingOfProtection(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"ring of protection"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('=', ColorEquipment, ROItem),
+		Collectible{},
+		Equippable{SlotRing},
+		Defense{1},
+	)
+}
+
+func (g *game) NewRingOfStrength(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"ring of strength"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('=', ColorEquipment, ROItem),
+		Collectible{},
+		Equippable{SlotRing},
+		Damage{1, 2},
+	)
+}
+
+func (g *game) NewLantern(p gruid.Point) Entity {
+	return g.ECS.Create(
+		Name{"lantern"},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg('(', ColorEquipment, ROItem),
+		Collectible{},
+		Equippable{SlotLight},
+		LightSource{Radius: 15, Intensity: 1.0},
+	)
+}
+
+// NewStairs creates a staircase leading to the next level if down is true, and
+// to the previous one otherwise.
+func (g *game) NewStairs(p gruid.Point, down bool) Entity {
+	r, name := '<', "staircase up"
+	if down {
+		r, name = '>', "staircase down"
+	}
+	return g.ECS.Create(
+		Name{name},
+		Position{p},
+		Visible{},
+		NewRenderableNoBg(r, ColorStairs, ROItem),
+		Stairs{down: down},
+	)
+}
+
+func (g *game) NewTorch(p gruid.Point) Entity {
 	return g.ECS.Create(
 		Name{"torch"},
 		Position{p},
@@ -129,7 +514,7 @@ func (g *game) NewTorch(p gruid.Point) int {
 	)
 }
 
-func (g *game) NewGrass(p gruid.Point) int {
+func (g *game) NewGrass(p gruid.Point) Entity {
 	return g.ECS.Create(
 		Position{p},
 		Visible{},
@@ -137,7 +522,7 @@ func (g *game) NewGrass(p gruid.Point) int {
 	)
 }
 
-func (g *game) NewWaterTile(p gruid.Point) int {
+func (g *game) NewWaterTile(p gruid.Point) Entity {
 	return g.ECS.Create(
 		Name{"water"},
 		Visible{},
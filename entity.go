@@ -0,0 +1,38 @@
+package main
+
+import "fmt"
+
+// Entity is a handle to an entity in the ECS. The low 32 bits hold the
+// entity's index, which is recycled after the entity is deleted, and the high
+// 32 bits hold the generation of that index. Deleting an entity bumps the
+// generation of its index, so stale handles kept around by inventories,
+// damage sources or targeting can be told apart from the entity that reuses
+// the index (see ECS.Alive).
+//
+// The player is always created first, so its handle is the zero Entity.
+type Entity uint64
+
+func newEntity(index, gen uint32) Entity {
+	return Entity(uint64(gen)<<32 | uint64(index))
+}
+
+// Index returns the entity's slot index.
+func (e Entity) Index() int {
+	return int(uint32(e))
+}
+
+// Gen returns the entity's generation.
+func (e Entity) Gen() uint32 {
+	return uint32(e >> 32)
+}
+
+func (e Entity) String() string {
+	return fmt.Sprintf("%d#%d", e.Index(), e.Gen())
+}
+
+// entitySlot records the current generation of an entity index, and whether
+// an entity currently occupies it.
+type entitySlot struct {
+	gen   uint32
+	alive bool
+}
@@ -0,0 +1,158 @@
+// Equipment: weapons, armor, rings and light sources that entities wear or
+// wield from their inventory. Equipped items stay in the inventory, marked
+// with an Equipped component, so that they follow their owner across levels
+// like any other item.
+
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Slot is where an item is worn or wielded. Only one item fits in each slot.
+type Slot int
+
+const (
+	SlotWeapon Slot = iota
+	SlotArmor
+	SlotRing
+	SlotLight
+)
+
+const CostEquip = 100
+
+var (
+	ErrNotEquippable = errors.New("You cannot wear or wield that.")
+	ErrNotEquipped   = errors.New("You are not using that.")
+)
+
+// equippedIn returns the item e wears or wields in the given slot, if any.
+func (ecs *ECS) equippedIn(e Entity, slot Slot) (Entity, bool) {
+	inv, ok := Get[Inventory](ecs, e)
+	if !ok {
+		return 0, false
+	}
+	for _, k := range sortedInventoryKeys(inv) {
+		it := inv.items[k]
+		if !ecs.Alive(it) || !ecs.HasComponent(it, Equipped{}) {
+			continue
+		}
+		if GetComponent[Equippable](ecs, it).slot == slot {
+			return it, true
+		}
+	}
+	return 0, false
+}
+
+// wearVerbs returns the verbs used for putting on and taking off items in
+// the given slot, and the state of items in use there.
+func wearVerbs(slot Slot) (on, off, state string) {
+	switch slot {
+	case SlotWeapon, SlotLight:
+		return "wield", "put away", "wielded"
+	}
+	return "wear", "take off", "worn"
+}
+
+// thirdPerson conjugates a verb, possibly followed by a particle, as in "take
+// off", in the third person.
+func thirdPerson(verb string) string {
+	v, particle, ok := strings.Cut(verb, " ")
+	if !ok {
+		return verb + "s"
+	}
+	return v + "s " + particle
+}
+
+// Equip makes e wear or wield the item in its inventory slot key, removing
+// whatever it was using in the same slot.
+func (ecs *ECS) Equip(e Entity, key rune) error {
+	inv := GetComponent[Inventory](ecs, e)
+	it, ok := inv.items[key]
+	if !ok || !ecs.Alive(it) {
+		return ErrNoItem
+	}
+	eq, ok := Get[Equippable](ecs, it)
+	if !ok {
+		return ErrNotEquippable
+	}
+	if ecs.HasComponent(it, Equipped{}) {
+		return nil
+	}
+	if old, ok := ecs.equippedIn(e, eq.slot); ok {
+		ecs.unequip(e, old)
+	}
+	on, _, _ := wearVerbs(eq.slot)
+	name := GetComponent[Name](ecs, it).string
+	ecs.report(e, "You "+on+" the %s.", thirdPerson(on)+" the %s.", name)
+	ecs.AddComponent(it, Equipped{})
+	ecs.Spend(e, CostEquip)
+	return nil
+}
+
+// Unequip makes e stop using the item in its inventory slot key.
+func (ecs *ECS) Unequip(e Entity, key rune) error {
+	inv := GetComponent[Inventory](ecs, e)
+	it, ok := inv.items[key]
+	if !ok || !ecs.Alive(it) {
+		return ErrNoItem
+	}
+	if !ecs.HasComponent(it, Equipped{}) {
+		return ErrNotEquipped
+	}
+	ecs.unequip(e, it)
+	ecs.Spend(e, CostEquip)
+	return nil
+}
+
+func (ecs *ECS) unequip(e, it Entity) {
+	_, off, _ := wearVerbs(GetComponent[Equippable](ecs, it).slot)
+	name := GetComponent[Name](ecs, it).string
+	ecs.report(e, "You "+off+" the %s.", thirdPerson(off)+" the %s.", name)
+	ecs.RemoveComponent(it, Equipped{})
+}
+
+// attackPower returns the damage range of e in melee, weapon and rings
+// included.
+func (ecs *ECS) attackPower(e Entity) Damage {
+	dmg := GetComponent[Damage](ecs, e)
+	for _, slot := range []Slot{SlotWeapon, SlotRing} {
+		if it, ok := ecs.equippedIn(e, slot); ok {
+			bonus, _ := Get[Damage](ecs, it)
+			dmg = dmg.add(bonus)
+		}
+	}
+	return dmg
+}
+
+// defense returns how much damage is taken off each hit on e, including the
+// armor and rings it wears.
+func (ecs *ECS) defense(e Entity) int {
+	def, _ := Get[Defense](ecs, e)
+	for _, slot := range []Slot{SlotArmor, SlotRing} {
+		if it, ok := ecs.equippedIn(e, slot); ok {
+			bonus, _ := Get[Defense](ecs, it)
+			def.int += bonus.int
+		}
+	}
+	return def.int
+}
+
+// weapon returns the name of the weapon e wields, if any.
+func (ecs *ECS) weapon(e Entity) (string, bool) {
+	w, ok := ecs.equippedIn(e, SlotWeapon)
+	if !ok {
+		return "", false
+	}
+	return GetComponent[Name](ecs, w).string, true
+}
+
+// lightOf returns the light e carries: the light source it wields, if any,
+// and its own otherwise.
+func (ecs *ECS) lightOf(e Entity) (LightSource, bool) {
+	if it, ok := ecs.equippedIn(e, SlotLight); ok {
+		return Get[LightSource](ecs, it)
+	}
+	return Get[LightSource](ecs, e)
+}
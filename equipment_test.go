@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEquipment(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	if got := g.ECS.attackPower(0); got != (Damage{3, 7}) {
+		t.Errorf("attack power %v with the starting sword, want 3-7", got)
+	}
+	g.NewDagger(origin)
+	g.NewChainMail(origin)
+	h.keys("g", "i")
+	if !strings.Contains(h.screen(), "sword (wielded)") {
+		t.Errorf("inventory does not show the wielded sword:\n%s", h.screen())
+	}
+	// Wielding the dagger puts the sword away.
+	h.keys("b", "i", "c")
+	if !h.logged("You put away the sword.") || !h.logged("You wield the dagger.") {
+		t.Errorf("wielding not logged: %v", g.Log)
+	}
+	if got := g.ECS.attackPower(0); got != (Damage{2, 4}) {
+		t.Errorf("attack power %v with a dagger, want 2-4", got)
+	}
+	if got := g.ECS.defense(0); got != 2 {
+		t.Errorf("defense %d in chain mail, want 2", got)
+	}
+	goblin := g.NewGoblin(origin.Shift(1, 0))
+	g.ECS.RemoveComponent(goblin, AI{})
+	h.keys("l")
+	if !h.logged("You hit the goblin with your dagger!") {
+		t.Errorf("attack does not name the dagger: %v", g.Log)
+	}
+	// Removing it from the inventory menu leaves the player bare-handed.
+	h.keys("i", "b")
+	if _, ok := g.ECS.weapon(0); ok {
+		t.Errorf("player still wields a weapon")
+	}
+	h.keys("l")
+	if !h.logged("You punch the goblin!") {
+		t.Errorf("bare-handed attack not logged: %v", g.Log)
+	}
+	// Dropped armor is no longer worn.
+	h.keys("d", "c")
+	if got := g.ECS.defense(0); got != 0 {
+		t.Errorf("defense %d after dropping the armor, want 0", got)
+	}
+}
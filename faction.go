@@ -0,0 +1,56 @@
+// Factions, and how they relate to each other. Mobs hunt the nearest entity
+// of a hostile faction, and swap places with allies rather than attacking
+// them.
+
+package main
+
+type faction string
+
+const (
+	FactionPlayer faction = "PLAYER"
+	FactionGoblin faction = "GOBLIN"
+	FactionTroll  faction = "TROLL"
+)
+
+// Relation describes how the members of two factions treat each other.
+type Relation int
+
+const (
+	Neutral Relation = iota // Ignore each other.
+	Hostile                 // Attack each other on sight.
+	Allied                  // Make way for each other.
+)
+
+// relations lists the relations between different factions. Each pair is
+// listed once, in any order. Pairs not listed are neutral, and members of
+// the same faction are allied.
+var relations = map[[2]faction]Relation{
+	{FactionPlayer, FactionGoblin}: Hostile,
+	{FactionPlayer, FactionTroll}:  Hostile,
+	{FactionGoblin, FactionTroll}:  Hostile, // Trolls eat goblins.
+}
+
+// relationOf returns the relation between two factions.
+func relationOf(a, b faction) Relation {
+	if a == b {
+		return Allied
+	}
+	if r, ok := relations[[2]faction{a, b}]; ok {
+		return r
+	}
+	return relations[[2]faction{b, a}]
+}
+
+// Relation returns the relation between two entities. Entities without a
+// faction are neutral to everyone.
+func (ecs *ECS) Relation(a, b Entity) Relation {
+	fa, ok := Get[Faction](ecs, a)
+	if !ok {
+		return Neutral
+	}
+	fb, ok := Get[Faction](ecs, b)
+	if !ok {
+		return Neutral
+	}
+	return relationOf(fa.faction, fb.faction)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+func TestFactions(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	// Out of the player's sight, a goblin and a troll fight each other.
+	goblin := g.NewGoblin(gruid.Point{X: 40, Y: 10})
+	troll := g.NewTroll(gruid.Point{X: 42, Y: 10})
+	h.keys(".", ".", ".", ".")
+	if ai := GetComponent[AI](g.ECS, troll); ai.target != goblin {
+		t.Errorf("troll targets %d, want the goblin %d", ai.target, goblin)
+	}
+	if !h.logged("The troll hits the goblin.") && !h.logged("The goblin hits the troll.") {
+		t.Errorf("goblin and troll did not fight: %v", g.Log)
+	}
+	// Goblins make way for each other.
+	h.arena(origin)
+	a := g.NewGoblin(gruid.Point{X: 40, Y: 10})
+	b := g.NewGoblin(gruid.Point{X: 41, Y: 10})
+	g.ECS.AddComponent(a, Bump{gruid.Point{X: 1}})
+	g.ECS.BumpSystem.Update(a)
+	pa := GetComponent[Position](g.ECS, a).Point
+	pb := GetComponent[Position](g.ECS, b).Point
+	if pa != (gruid.Point{X: 41, Y: 10}) || pb != (gruid.Point{X: 40, Y: 10}) {
+		t.Errorf("goblins at %v and %v, want swapped", pa, pb)
+	}
+}
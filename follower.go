@@ -0,0 +1,228 @@
+// Followers: monsters won over by the player, e.g. with a scroll of charming.
+// They join the player's faction, follow them around, help them fight, and
+// obey simple orders given from the orders menu.
+
+package main
+
+import (
+	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/paths"
+	"codeberg.org/anaseto/gruid/ui"
+)
+
+// Order is an order given by the player to their followers.
+type Order int
+
+const (
+	OrderFollow Order = iota // Follow the player, and help them fight.
+	OrderStay                // Stay put, only fighting back adjacent enemies.
+	OrderAttack              // Attack the target, then follow again.
+)
+
+// FollowDistance is how close followers try to stay to the player.
+const FollowDistance = 2
+
+// orderEntries lists the orders of the orders menu, in menu order.
+var orderEntries = []struct {
+	order Order
+	key   gruid.Key
+	text  string
+}{
+	{OrderFollow, "f", "f - Follow me"},
+	{OrderStay, "s", "s - Stay here"},
+	{OrderAttack, "a", "a - Attack target"},
+}
+
+// Recruit turns the monster e into a follower of the player. It returns false
+// if e cannot become one.
+func (g *game) Recruit(e Entity) bool {
+	if e == 0 || !g.ECS.HasComponents(e, AI{}, Health{}) || g.ECS.HasComponent(e, Follower{}) {
+		return false
+	}
+	ids := defaultGoals
+	if gl, ok := Get[Goals](g.ECS, e); ok {
+		ids = gl.ids
+	}
+	g.ECS.RemoveComponent(e, Pack{})
+	g.ECS.AddComponents(e,
+		Faction{FactionPlayer},
+		Follower{order: OrderFollow},
+		AI{state: CSFollowing},
+		Goals{append(append([]goalID{}, ids...), followerGoals...)},
+	)
+	if r, ok := Get[Renderable](g.ECS, e); ok {
+		r.cell.Style.Fg = ColorAlly
+		g.ECS.AddComponent(e, r)
+	}
+	g.Logf("The %s is now your ally.", ColorLogSpecial, GetComponent[Name](g.ECS, e).string)
+	return true
+}
+
+// GiveOrder gives an order to all the followers on the level. For OrderAttack,
+// p is the position of the monster to attack.
+func (g *game) GiveOrder(o Order, p gruid.Point) {
+	followers := g.ECS.EntitiesWith(Follower{})
+	if len(followers) == 0 {
+		g.Logf("You have no followers.", ColorLogSpecial)
+		return
+	}
+	var target Entity
+	switch o {
+	case OrderFollow:
+		g.Logf("You call your followers to your side.", ColorLogSpecial)
+	case OrderStay:
+		g.Logf("You order your followers to stay.", ColorLogSpecial)
+	case OrderAttack:
+		found := false
+		for _, e := range g.ECS.EntitiesAtPWith(p, Health{}) {
+			if g.InFOV(p) && g.ECS.Relation(0, e) == Hostile {
+				target, found = e, true
+			}
+		}
+		if !found {
+			g.Logf("There is nothing to attack there.", ColorLogSpecial)
+			return
+		}
+		g.Logf("You order your followers to attack the %s.", ColorLogSpecial, GetComponent[Name](g.ECS, target).string)
+	}
+	for _, e := range followers {
+		g.ECS.AddComponent(e, Follower{order: o, target: target})
+		if o == OrderStay {
+			g.ECS.AddComponent(e, Guard{post: GetComponent[Position](g.ECS, e).Point})
+		} else {
+			g.ECS.RemoveComponent(e, Guard{})
+		}
+	}
+}
+
+// assist makes the followers that are following the player help them fight
+// target.
+func (ecs *ECS) assist(target Entity) {
+	for _, e := range ecs.EntitiesWith(Follower{}) {
+		f := GetComponent[Follower](ecs, e)
+		if f.order == OrderFollow {
+			f.target = target
+			ecs.AddComponent(e, f)
+		}
+	}
+}
+
+func scoreGuard(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if ai.state == CSSleeping || !s.ecs.HasComponent(e, Guard{}) {
+		return 0
+	}
+	return 800
+}
+
+// actGuard fights back adjacent enemies, and otherwise goes back to the post.
+func actGuard(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	if tp, ok := s.targetPos(ai); ok && ai.state == CSHunting && paths.DistanceChebyshev(p, tp) <= 1 {
+		s.moveTo(e, ai, p, tp)
+		return
+	}
+	if post := GetComponent[Guard](s.ecs, e).post; post != p && s.moveTo(e, ai, p, post) {
+		return
+	}
+	actWait(s, e, ai, p)
+}
+
+// scoreAssist scores attacking the follower's target. Followers forget about
+// their target once it is dead, and those ordered to attack it go back to
+// following the player.
+func scoreAssist(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	f, ok := Get[Follower](s.ecs, e)
+	if !ok || f.target == 0 {
+		return 0
+	}
+	if s.ecs.HasComponents(f.target, Position{}, Health{}) {
+		return 750
+	}
+	f.target = 0
+	if f.order == OrderAttack {
+		f.order = OrderFollow
+	}
+	s.ecs.AddComponent(e, f)
+	return 0
+}
+
+func actAssist(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	ai.state, ai.target = CSHunting, GetComponent[Follower](s.ecs, e).target
+	if scoreSkirmish(s, e, ai, p) > 0 {
+		actSkirmish(s, e, ai, p)
+		return
+	}
+	actHunt(s, e, ai, p)
+}
+
+func scoreFollow(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if ai.state != CSFollowing || !s.ecs.HasComponent(0, Position{}) {
+		return 0
+	}
+	return 500
+}
+
+// actFollow keeps the follower within FollowDistance of the player.
+func actFollow(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	pp := GetComponent[Position](s.ecs, 0).Point
+	if paths.DistanceChebyshev(p, pp) <= FollowDistance || !s.moveTo(e, ai, p, pp) {
+		actWait(s, e, ai, p)
+	}
+}
+
+// bringFollowers moves the followers that were following the player close to
+// from, on the level the player left, to the current level, next to the
+// player.
+func (g *game) bringFollowers(ecs *ECS, from gruid.Point) {
+	for _, e := range ecs.EntitiesWith(Follower{}, Position{}) {
+		f := GetComponent[Follower](ecs, e)
+		p := GetComponent[Position](ecs, e).Point
+		if f.order != OrderFollow || paths.DistanceChebyshev(p, from) > FollowDistance {
+			continue
+		}
+		ne := g.ECS.Create()
+		ecs.moveInventory(e, g.ECS)
+		ecs.moveComponents(e, g.ECS, ne)
+		ecs.Delete(e)
+		g.ECS.AddComponents(ne,
+			Position{g.freeTileNear(g.PlayerPosition())},
+			Follower{order: OrderFollow},
+			AI{state: CSFollowing},
+		)
+	}
+}
+
+// OpenOrders opens the menu of orders that can be given to followers.
+func (m *model) OpenOrders() {
+	entries := []ui.MenuEntry{}
+	for _, o := range orderEntries {
+		entries = append(entries, ui.MenuEntry{
+			Text: ui.Text(o.text),
+			Keys: []gruid.Key{o.key},
+		})
+	}
+	m.orders = ui.NewMenu(ui.MenuConfig{
+		Grid:    gruid.NewGrid(40, len(entries)+2),
+		Box:     &ui.Box{Title: ui.Text("Orders").WithStyle(gruid.Style{}.WithFg(ColorAlly))},
+		Entries: entries,
+	})
+	m.mode = modeOrders
+}
+
+// updateOrders handles input messages when the orders menu is open.
+func (m *model) updateOrders(msg gruid.Msg) {
+	m.orders.Update(msg)
+	switch m.orders.Action() {
+	case ui.MenuQuit:
+		m.mode = modeNormal
+	case ui.MenuInvoke:
+		o := orderEntries[m.orders.Active()].order
+		m.mode = modeNormal
+		if o == OrderAttack {
+			// Choose the target first.
+			m.target = &targeting{pos: m.game.PlayerPosition(), order: true}
+			m.mode = modeTargeting
+			return
+		}
+		m.action = action{Type: ActionOrder, Order: o}
+	}
+}
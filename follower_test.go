@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/paths"
+)
+
+func TestFollowers(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	goblin := g.NewGoblin(origin.Shift(1, 0))
+	if !g.Recruit(goblin) {
+		t.Fatal("could not recruit the goblin")
+	}
+	// The player swaps places with their follower instead of hitting it.
+	h.keys("l")
+	if pos := GetComponent[Position](g.ECS, goblin).Point; pos != origin || h.playerPos() != origin.Shift(1, 0) {
+		t.Errorf("player at %v, goblin at %v", h.playerPos(), pos)
+	}
+	if hp := GetComponent[Health](g.ECS, goblin); hp.hp != hp.maxhp {
+		t.Errorf("player hit their follower")
+	}
+	// It follows the player around.
+	h.keys("j", "j", "j", "j", "j", "j")
+	if d := paths.DistanceChebyshev(h.playerPos(), GetComponent[Position](g.ECS, goblin).Point); d > FollowDistance+1 {
+		t.Errorf("follower %d tiles away", d)
+	}
+	// Unless told to stay.
+	h.keys("o", "s")
+	stay := GetComponent[Position](g.ECS, goblin).Point
+	h.keys("k", "k", "k", "k")
+	if pos := GetComponent[Position](g.ECS, goblin).Point; pos != stay {
+		t.Errorf("follower moved from %v to %v while staying", stay, pos)
+	}
+	// Told to attack, it goes for the target.
+	troll := g.NewTroll(h.playerPos().Shift(4, 0))
+	g.ECS.RemoveComponent(troll, AI{})
+	g.ECS.FOVSystem.Update(0)
+	h.keys("o", "a")
+	for range 4 {
+		h.keys("l")
+	}
+	h.keys(gruid.KeyEnter)
+	if f := GetComponent[Follower](g.ECS, goblin); f.order != OrderAttack || f.target != troll {
+		t.Fatalf("follower order %d target %d, want attack %d", f.order, f.target, troll)
+	}
+	h.keys(".", ".", ".", ".", ".", ".", ".", ".")
+	if !h.logged("The goblin hits the troll.") {
+		t.Errorf("follower did not attack: %v", g.Log)
+	}
+}
@@ -1,22 +1,110 @@
 package main
 
 import (
+	"math/rand/v2"
+
 	"codeberg.org/anaseto/gruid"
 	"codeberg.org/anaseto/gruid/rl"
 )
 
 type game struct {
-	ECS *ECS
-	Map *Map
-	Log []LogEntry
+	ECS     *ECS     // ECS of the current level.
+	Map     *Map     // Map of the current level.
+	Levels  []*level // Levels visited so far, by depth - 1.
+	Depth   int      // Depth of the current level, starting at 1.
+	Log     []LogEntry
+	Seed    uint64   // Seed driving every random roll of the run.
+	History []action // Actions played since the start of the run.
 }
 
-const (
-	MonstersToSpawn = 4
-	ScrollsToPlace  = 3
-	PotionsToPlace  = 3
-	TorchesToPlace  = 5
-)
+const TorchesToPlace = 5
+
+// spawnEntry describes a kind of entity that can be spawned at random, and
+// how common it is depending on the depth.
+type spawnEntry struct {
+	minDepth int // Shallowest depth at which it appears.
+	weight   int // Weight at minDepth.
+	perDepth int // Weight gained (or lost) per level below minDepth.
+	spawn    func(g *game, p gruid.Point) Entity
+	pack     *pack // Members spawned around the entity, if any.
+}
+
+// pack describes the members of a group spawned around its leader.
+type pack struct {
+	member   func(g *game, p gruid.Point) Entity
+	min, max int // Number of members, besides the leader.
+}
+
+// weightAt returns the weight of the entry at the given depth.
+func (se spawnEntry) weightAt(depth int) int {
+	if depth < se.minDepth {
+		return 0
+	}
+	return max(se.weight+se.perDepth*(depth-se.minDepth), 0)
+}
+
+// Monsters get tougher with depth: trolls slowly replace goblins.
+var monsterTable = []spawnEntry{
+	{minDepth: 1, weight: 80, perDepth: -10, spawn: (*game).NewGoblin},
+	{minDepth: 1, weight: 20, perDepth: 15, spawn: (*game).NewTroll},
+	{minDepth: 2, weight: 15, perDepth: 5, spawn: (*game).NewGoblinArcher},
+	{minDepth: 3, weight: 10, perDepth: 5, spawn: (*game).NewGoblinShaman},
+	{minDepth: 2, weight: 15, perDepth: 5, spawn: (*game).NewGoblinChief,
+		pack: &pack{member: (*game).NewGoblin, min: 2, max: 4}},
+}
+
+var itemTable = []spawnEntry{
+	{minDepth: 1, weight: 100, spawn: (*game).NewHealthPotion},
+	{minDepth: 2, weight: 30, perDepth: 10, spawn: (*game).NewScroll},
+	{minDepth: 1, weight: 15, spawn: (*game).NewScrollOfCharming},
+	{minDepth: 2, weight: 10, perDepth: 2, spawn: (*game).NewPotionOfFire},
+	{minDepth: 2, weight: 10, perDepth: 2, spawn: (*game).NewPotionOfConfusion},
+	{minDepth: 1, weight: 5, perDepth: 1, spawn: (*game).NewPotionOfRegeneration},
+	{minDepth: 2, weight: 5, perDepth: 1, spawn: (*game).NewPotionOfHaste},
+	{minDepth: 2, weight: 5, perDepth: 1, spawn: (*game).NewPotionOfLevitation},
+	{minDepth: 2, weight: 5, perDepth: 2, spawn: (*game).NewPotionOfPoison},
+	{minDepth: 3, weight: 5, perDepth: 2, spawn: (*game).NewPotionOfBlindness},
+	{minDepth: 3, weight: 5, perDepth: 2, spawn: (*game).NewPotionOfParalysis},
+	{minDepth: 2, weight: 5, perDepth: 2, spawn: (*game).NewWandOfSlowness},
+	{minDepth: 2, weight: 5, perDepth: 2, spawn: (*game).NewWandOfEntrancement},
+	{minDepth: 3, weight: 5, perDepth: 2, spawn: (*game).NewWandOfLightning},
+	{minDepth: 3, weight: 3, perDepth: 1, spawn: (*game).NewStaffOfFirebolt},
+	{minDepth: 3, weight: 3, perDepth: 1, spawn: (*game).NewStaffOfBlinking},
+	{minDepth: 1, weight: 10, spawn: (*game).NewDagger},
+	{minDepth: 1, weight: 10, spawn: (*game).NewLeatherArmor},
+	{minDepth: 1, weight: 5, spawn: (*game).NewLantern},
+	{minDepth: 2, weight: 5, perDepth: 2, spawn: (*game).NewSword},
+	{minDepth: 3, weight: 5, perDepth: 2, spawn: (*game).NewChainMail},
+	{minDepth: 3, weight: 5, spawn: (*game).NewRingOfProtection},
+	{minDepth: 3, weight: 5, spawn: (*game).NewRingOfStrength},
+}
+
+// monstersAt returns the number of monsters spawned on a level.
+func monstersAt(depth int) int {
+	return 3 + depth
+}
+
+// itemsAt returns the number of items spawned on a level.
+func itemsAt(depth int) int {
+	return 3 + depth/2
+}
+
+// roll picks an entry of the table at random, according to their weights at
+// the given depth.
+func (g *game) roll(table []spawnEntry, depth int) spawnEntry {
+	total := 0
+	for _, se := range table {
+		total += se.weightAt(depth)
+	}
+	n := g.Map.Rand.IntN(total)
+	for _, se := range table {
+		n -= se.weightAt(depth)
+		if n < 0 {
+			return se
+		}
+	}
+	panic("unreachable")
+}
 
 var Directions = []gruid.Point{
 	{X: 0, Y: -1},  // N
@@ -29,19 +117,18 @@ var Directions = []gruid.Point{
 	{X: -1, Y: 1},  // SW
 }
 
+// Initialize generates a new game from g.Seed. If no seed was set, a random
+// one is picked, so that the run can still be reproduced later.
 func (g *game) Initialize() {
-	// Initialize map and ECS.
-	g.Map = NewMap(gruid.Point{X: MapWidth, Y: MapHeight})
-	g.ECS = NewECS()
-	g.ECS.Map = g.Map
-	// Place player on a random floor.
-	g.NewPlayer(g.FreeFloorTile())
-	g.SpawnTorches()
-	g.SpawnPotions()
-	g.SpawnEnemies()
-	g.SpawnGrass()
+	for g.Seed == 0 {
+		g.Seed = rand.Uint64()
+	}
+	g.Levels = nil
+	g.Depth = 1
+	g.newLevel(g.Depth)
+	// Place player on the entry of the first level.
+	g.NewPlayer(g.PlayerPosition())
 	g.ECS.Initialize()
-	g.ECS.LightingSystem.BakeTorchLighting()
 }
 
 func (g *game) Pathable(p gruid.Point) bool {
@@ -51,27 +138,33 @@ func (g *game) Pathable(p gruid.Point) bool {
 	return false
 }
 
-func (g *game) SpawnEnemies() {
-	for i := 0; i < MonstersToSpawn; i++ {
-		switch {
-		case g.Map.Rand.IntN(100) < 80:
-			g.NewGoblin(g.FreeFloorTile())
-		default:
-			g.NewTroll(g.FreeFloorTile())
+// SleepChance is the chance, in percent, that a monster spawns asleep.
+const SleepChance = 40
+
+// SpawnEnemies places monsters throughout the map during gen. Packs count
+// against the number of monsters of the level, and sleep or not together.
+func (g *game) SpawnEnemies(depth int) {
+	for n := 0; n < monstersAt(depth); {
+		se := g.roll(monsterTable, depth)
+		group := []Entity{se.spawn(g, g.FreeFloorTile())}
+		if se.pack != nil {
+			group = g.spawnPack(group[0], *se.pack)
+		}
+		n += len(group)
+		if g.Map.Rand.IntN(100) < SleepChance {
+			for _, e := range group {
+				ai := GetComponent[AI](g.ECS, e)
+				ai.state = CSSleeping
+				g.ECS.AddComponent(e, ai)
+			}
 		}
 	}
 }
 
 // Places potions and other items throughout the map during gen.
-func (g *game) SpawnPotions() {
-	for i := 0; i < PotionsToPlace; i++ {
-		g.NewHealthPotion(g.FreeFloorTile())
-	}
-}
-
-func (g *game) SpawnScrolls() {
-	for i := 0; i < ScrollsToPlace; i++ {
-		g.NewScroll(g.FreeFloorTile())
+func (g *game) SpawnItems(depth int) {
+	for i := 0; i < itemsAt(depth); i++ {
+		g.roll(itemTable, depth).spawn(g, g.FreeFloorTile())
 	}
 }
 
@@ -89,7 +182,8 @@ func (g *game) SpawnGrass() {
 	it := scratch.Iterator()
 	for it.Next() {
 		p := it.P()
-		if it.Cell() == GrassFloor && g.Map.Grid.At(p) == Floor && g.ECS.NoBlockingEntityAt(p) {
+		if it.Cell() == GrassFloor && g.Map.Grid.At(p) == Floor && g.ECS.NoBlockingEntityAt(p) &&
+			len(g.ECS.EntitiesAtPWith(p, Stairs{})) == 0 {
 			g.NewGrass(p)
 		}
 	}
@@ -108,25 +202,6 @@ func (g *game) SpawnCorpses() {
 	}
 }
 
-func (g *game) PickupItem() (ok bool) {
-	// Right now only looking at entities that have both input and inventory (player)
-	// but want to write way of doing this that doesn't care about input
-	ok = false
-	p := g.PlayerPosition()
-	inv := g.PlayerInventory()
-	for _, i := range g.ECS.EntitiesAtPWith(p, Collectible{}) {
-		// There is an item here that is collectible! Place a reference to it
-		// in e's inventory and remove its Position component.
-		ok = true
-		item_name := GetComponent[Name](g.ECS, i).string
-		g.Logf("You pick up the %s.", ColorLogSpecial, item_name)
-		inv.items[inv.nextKey()] = i
-		g.ECS.RemoveComponent(i, Position{})
-	}
-	g.ECS.AddComponent(0, inv)
-	return ok
-}
-
 // Returns a free floor tile in the map.
 func (g *game) FreeFloorTile() gruid.Point {
 	for {
@@ -147,7 +222,9 @@ func (g *game) PlayerPosition() gruid.Point {
 
 func (g *game) PlayerInventory() Inventory {
 	if inv, hasInv := g.ECS.GetComponent(0, Inventory{}); hasInv {
-		return inv.(Inventory)
+		inv := inv.(Inventory)
+		inv.removeStale(g.ECS)
+		return inv
 	}
 	return Inventory{}
 }
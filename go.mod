@@ -6,14 +6,19 @@ require (
 	codeberg.org/anaseto/gruid v0.24.0
 	codeberg.org/anaseto/gruid-js v0.3.0
 	codeberg.org/anaseto/gruid-sdl v0.6.0
+	github.com/gdamore/tcell/v2 v2.13.10
 	github.com/k0kubun/pp/v3 v3.5.0
 	golang.org/x/image v0.29.0
 )
 
 require (
+	github.com/gdamore/encoding v1.0.1 // indirect
+	github.com/lucasb-eyer/go-colorful v1.3.0 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/veandco/go-sdl2 v0.4.40 // indirect
-	golang.org/x/sys v0.29.0 // indirect
-	golang.org/x/text v0.27.0 // indirect
+	golang.org/x/sys v0.38.0 // indirect
+	golang.org/x/term v0.37.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
 )
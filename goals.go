@@ -0,0 +1,258 @@
+// The decision layer of the AI. Each turn, a mob perceives its surroundings
+// (see PerceptionSystem), then scores each of its goals, and pursues the one
+// that scores highest. Pursuing a goal gives the mob an action component,
+// such as Bump, Shoot or Action, that the systems then carry out. Monster
+// types define which goals they have, so that new behaviors only take a new
+// goal, registered in goals, and added to the monsters that should have it.
+
+package main
+
+import (
+	"fmt"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+// goalID identifies a goal. IDs are saved along with the mobs having them.
+type goalID string
+
+const (
+	GoalSleep    goalID = "sleep"    // Sleep until woken up.
+	GoalQuaff    goalID = "quaff"    // Drink a healing item when wounded.
+	GoalGuard    goalID = "guard"    // Stay at a post, fighting adjacent enemies.
+	GoalAssist   goalID = "assist"   // Attack the target the player points out.
+	GoalFlee     goalID = "flee"     // Run away from the player when wounded.
+	GoalSkirmish goalID = "skirmish" // Shoot from afar, backing off if needed.
+	GoalHunt     goalID = "hunt"     // Close in on the target and attack it.
+	GoalFollow   goalID = "follow"   // Stay close to the player.
+	GoalPickup   goalID = "pickup"   // Pick up the items underfoot.
+	GoalFetch    goalID = "fetch"    // Walk to items in sight.
+	GoalSearch   goalID = "search"   // Look for a target out of sight.
+	GoalRegroup  goalID = "regroup"  // Stay close to the pack's leader.
+	GoalWander   goalID = "wander"   // Roam the level.
+)
+
+// goal is something a mob may pursue on its turn. score returns how much the
+// mob at p wants to pursue it right now, 0 meaning not at all. act pursues it,
+// usually by giving the mob an action component. Both may update ai, which is
+// saved afterwards.
+type goal struct {
+	score func(s *AISystem, e Entity, ai *AI, p gruid.Point) int
+	act   func(s *AISystem, e Entity, ai *AI, p gruid.Point)
+}
+
+// goals holds every known goal. Scores are on a common scale, so that urgent
+// goals take precedence over idle ones.
+var goals = map[goalID]goal{
+	GoalSleep:    {scoreSleep, actWait},
+	GoalQuaff:    {scoreQuaff, actQuaff},
+	GoalGuard:    {scoreGuard, actGuard},
+	GoalAssist:   {scoreAssist, actAssist},
+	GoalFlee:     {scoreFlee, actFlee},
+	GoalSkirmish: {scoreSkirmish, actSkirmish},
+	GoalHunt:     {scoreHunt, actHunt},
+	GoalFollow:   {scoreFollow, actFollow},
+	GoalPickup:   {scorePickup, actPickup},
+	GoalFetch:    {scoreFetch, actFetch},
+	GoalSearch:   {scoreSearch, actSearch},
+	GoalRegroup:  {scoreRegroup, actRegroup},
+	GoalWander:   {scoreWander, actWander},
+}
+
+// Goals of the different kinds of mobs. Mobs without a Goals component have
+// the default ones.
+var (
+	defaultGoals = []goalID{GoalSleep, GoalFlee, GoalHunt, GoalSearch, GoalWander}
+	goblinGoals  = []goalID{GoalSleep, GoalQuaff, GoalFlee, GoalHunt, GoalPickup, GoalFetch, GoalSearch, GoalRegroup, GoalWander}
+	shooterGoals = append([]goalID{GoalSkirmish}, goblinGoals...)
+	// Added to the goals of recruited mobs.
+	followerGoals = []goalID{GoalGuard, GoalAssist, GoalFollow}
+)
+
+// Update lets an entity with AI{} and Position{} components pursue its most
+// pressing goal. If none applies, it waits.
+func (s *AISystem) Update(e Entity) {
+	if !s.ecs.HasComponents(e, Position{}, AI{}) {
+		return
+	}
+	ai := GetComponent[AI](s.ecs, e)
+	p := GetComponent[Position](s.ecs, e).Point
+	ids := defaultGoals
+	if g, ok := Get[Goals](s.ecs, e); ok {
+		ids = g.ids
+	}
+	var best *goal
+	bestScore := 0
+	for _, id := range ids {
+		g := goals[id]
+		if score := g.score(s, e, &ai, p); score > bestScore {
+			best, bestScore = &g, score
+		}
+	}
+	if best != nil {
+		best.act(s, e, &ai, p)
+	} else {
+		actWait(s, e, &ai, p)
+	}
+	s.ecs.AddComponent(e, ai)
+}
+
+// moveTo gives the mob at p a step toward dest. It returns false if dest
+// cannot be reached.
+func (s *AISystem) moveTo(e Entity, ai *AI, p, dest gruid.Point) bool {
+	ai.dest = &dest
+	// Recompute A* only when the destination changed or the cached path is
+	// exhausted. Otherwise advance along the existing path one step.
+	if ai.cachedDest == nil || *ai.cachedDest != dest || len(ai.cachedPath) <= 1 {
+		ai.cachedPath = s.ecs.Map.PR.AstarPath(s.aip, p, dest)
+		ai.cachedDest = &dest
+	}
+	if len(ai.cachedPath) <= 1 {
+		return false
+	}
+	q := ai.cachedPath[1]
+	ai.cachedPath = ai.cachedPath[1:]
+	s.ecs.AddComponent(e, Bump{q.Sub(p)})
+	return true
+}
+
+// targetPos returns the position of the mob's target, if it is still alive.
+func (s *AISystem) targetPos(ai *AI) (gruid.Point, bool) {
+	if !s.ecs.HasComponents(ai.target, Position{}, Health{}) {
+		return gruid.Point{}, false
+	}
+	return GetComponent[Position](s.ecs, ai.target).Point, true
+}
+
+func actWait(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	ai.dest = nil
+}
+
+func scoreSleep(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if ai.state == CSSleeping {
+		return 1000
+	}
+	return 0
+}
+
+func scoreFlee(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if ai.state != CSFleeing {
+		return 0
+	}
+	if _, ok := s.ecs.Map.SafetyMap.Downhill(p, s.ecs.NoBlockingEntityAt); !ok {
+		return 0 // Cornered: fight back.
+	}
+	return 700
+}
+
+// actFlee steps down the safety map, which leads away from the player and
+// around corners.
+func actFlee(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	q, _ := s.ecs.Map.SafetyMap.Downhill(p, s.ecs.NoBlockingEntityAt)
+	ai.dest, ai.cachedDest, ai.cachedPath = nil, nil, nil
+	s.ecs.AddComponent(e, Bump{q.Sub(p)})
+}
+
+func scoreHunt(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if ai.state != CSHunting && ai.state != CSFleeing {
+		return 0
+	}
+	if _, ok := s.targetPos(ai); !ok {
+		return 0
+	}
+	return 600
+}
+
+func actHunt(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	tp, _ := s.targetPos(ai)
+	s.moveTo(e, ai, p, tp)
+}
+
+func scoreSearch(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if ai.state == CSSearching {
+		return 200
+	}
+	return 0
+}
+
+// actSearch goes to where the target was last seen, then looks around for a
+// few turns, and finally gives up.
+func actSearch(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	if ai.lastSeen != nil && *ai.lastSeen != p {
+		if !s.moveTo(e, ai, p, *ai.lastSeen) {
+			// The last known position cannot be reached: look around
+			// here.
+			ai.lastSeen = nil
+		}
+		return
+	}
+	ai.lastSeen = nil
+	ai.search--
+	if ai.search <= 0 {
+		ai.state = CSWandering
+		ai.dest = nil
+		name := GetComponent[Name](s.ecs, e).string
+		target := "you"
+		if ai.target != 0 {
+			// The target may have been deleted since it was last seen.
+			if n, ok := Get[Name](s.ecs, ai.target); ok {
+				target = "the " + n.string
+			} else {
+				target = "its prey"
+			}
+			ai.target = 0
+		}
+		s.ecs.Create(LogEntry{Text: fmt.Sprintf("The %s loses track of %s.", name, target), Color: ColorLogSpecial})
+		return
+	}
+	free := []gruid.Point{}
+	for _, q := range s.aip.Neighbors(p) {
+		if s.ecs.NoBlockingEntityAt(q) {
+			free = append(free, q)
+		}
+	}
+	if len(free) == 0 {
+		return
+	}
+	s.moveTo(e, ai, p, free[s.ecs.Map.Rand.IntN(len(free))])
+}
+
+func scoreWander(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if ai.state == CSWandering {
+		return 100
+	}
+	return 0
+}
+
+// actWander heads for a far destination, picking a new one once there.
+func actWander(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	dest := ai.dest
+	if dest == nil || *dest == p {
+		f := s.farDestination(p)
+		dest = &f
+	}
+	if !s.moveTo(e, ai, p, *dest) {
+		ai.dest = nil // Pick another destination next time.
+	}
+}
+
+// farDestination picks a random destination among the tiles farthest from p,
+// so that wandering monsters roam across the level instead of jittering
+// around the same spots.
+func (s *AISystem) farDestination(p gruid.Point) gruid.Point {
+	if s.dm == nil {
+		s.dm = NewDijkstraMap(s.ecs.Map.Grid.Size())
+	}
+	s.dm.Compute(s.ecs.Map, []DijkstraGoal{{P: p}})
+	far := s.dm.Max() * 3 / 4
+	candidates := []gruid.Point{}
+	for i, d := range s.dm.dist {
+		if d != Unreachable && d > 0 && d >= far {
+			candidates = append(candidates, s.dm.point(i))
+		}
+	}
+	if len(candidates) == 0 {
+		return p
+	}
+	return candidates[s.ecs.Map.Rand.IntN(len(candidates))]
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/paths"
+)
+
+func TestSearching(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	// A wall splits the map in two, with the player on the left.
+	for y := range MapHeight {
+		g.Map.Grid.Set(gruid.Point{X: 20, Y: y}, Wall)
+	}
+	seen := gruid.Point{X: 25, Y: 10}
+	goblin := g.NewGoblin(gruid.Point{X: 30, Y: 15})
+	g.ECS.AddComponent(goblin, AI{state: CSHunting, lastSeen: &seen})
+	reached := false
+	for range 20 {
+		h.keys(".")
+		if GetComponent[Position](g.ECS, goblin).Point == seen {
+			reached = true
+		}
+		if GetComponent[AI](g.ECS, goblin).state == CSWandering {
+			break
+		}
+	}
+	if !reached {
+		t.Errorf("goblin never went to the last known position")
+	}
+	if state := GetComponent[AI](g.ECS, goblin).state; state != CSWandering {
+		t.Errorf("goblin still %s", state)
+	}
+	if !h.logged("The goblin loses track of you.") {
+		t.Errorf("giving up not logged: %v", g.Log)
+	}
+}
+
+// TestSearchingDeletedTarget checks that monsters give up searching for a
+// target that no longer exists.
+func TestSearchingDeletedTarget(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	troll := g.NewTroll(origin.Shift(10, 10))
+	goblin := g.NewGoblin(origin.Shift(5, 5))
+	g.ECS.AddComponent(goblin, Perception{LOS: 0})
+	g.ECS.AddComponent(goblin, AI{state: CSSearching, target: troll, search: 1})
+	g.ECS.Delete(troll)
+	h.keys(".")
+	ai := GetComponent[AI](g.ECS, goblin)
+	if ai.state == CSSearching || ai.target != 0 {
+		t.Errorf("goblin still searching for a deleted target: %+v", ai)
+	}
+	if !h.logged("The goblin loses track of its prey.") {
+		t.Errorf("giving up not logged: %v", g.Log)
+	}
+}
+
+func TestFleeing(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	goblin := g.NewGoblin(origin.Shift(1, 0))
+	g.ECS.AddComponent(goblin, Health{hp: 2, maxhp: 10})
+	dist := func() int {
+		return paths.DistanceChebyshev(h.playerPos(), GetComponent[Position](g.ECS, goblin).Point)
+	}
+	for i := range 5 {
+		h.keys(".")
+		if got := dist(); got != i+2 {
+			t.Fatalf("goblin at distance %d after %d turns, want %d", got, i+1, i+2)
+		}
+	}
+	if state := GetComponent[AI](g.ECS, goblin).state; state != CSFleeing {
+		t.Errorf("goblin %s, want %s", state, CSFleeing)
+	}
+	if !h.logged("The goblin flees!") {
+		t.Errorf("fleeing not logged: %v", g.Log)
+	}
+}
+
+func TestWandering(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	p := gruid.Point{X: 40, Y: 12}
+	for range 10 {
+		dest := g.ECS.AISystem.farDestination(p)
+		if d := paths.DistanceChebyshev(p, dest); d < 20 {
+			t.Errorf("wandering destination %v only %d tiles away from %v", dest, d, p)
+		}
+	}
+}
+
+func TestGoals(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	goblin := g.NewGoblin(origin.Shift(2, 0))
+	// A new goal only takes registering it and giving it to a mob.
+	goals["dance"] = goal{
+		score: func(s *AISystem, e Entity, ai *AI, p gruid.Point) int { return 2000 },
+		act: func(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+			s.ecs.AddComponent(e, Bump{gruid.Point{X: 0, Y: 1}})
+		},
+	}
+	defer delete(goals, "dance")
+	g.ECS.AddComponent(goblin, Goals{append([]goalID{"dance"}, goblinGoals...)})
+	h.keys(".")
+	if pos := GetComponent[Position](g.ECS, goblin).Point; pos != origin.Shift(2, 1) {
+		t.Errorf("goblin at %v, want it to dance to %v", pos, origin.Shift(2, 1))
+	}
+	// Without it, the goblin goes back to hunting the player.
+	g.ECS.AddComponent(goblin, Goals{goblinGoals})
+	h.keys(".", ".")
+	if hp := GetComponent[Health](g.ECS, 0); hp.hp == hp.maxhp {
+		t.Errorf("goblin did not attack: %v", g.Log)
+	}
+}
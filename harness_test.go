@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+// origin is a floor position, away from the walls, where tests place the
+// player and their surroundings.
+var origin = gruid.Point{X: 10, Y: 10}
+
+// harness drives a model directly, without any gruid.App, so that tests can
+// script input and inspect both the drawn grid and the ECS.
+type harness struct {
+	t *testing.T
+	m *model
+}
+
+// newHarness returns a harness for a new game generated from seed. Saving
+// and recording are disabled.
+func newHarness(t *testing.T, seed uint64) *harness {
+	t.Helper()
+	gd := gruid.NewGrid(UIWidth, UIHeight)
+	h := &harness{t: t, m: NewModel(gd, config{Seed: seed})}
+	h.send(gruid.MsgInit{})
+	return h
+}
+
+// send passes msg to the model. Commands and subscriptions returned by the
+// model, such as the frame ticker, are ignored: see tick.
+func (h *harness) send(msg gruid.Msg) {
+	h.t.Helper()
+	h.m.Update(msg)
+}
+
+// keys sends a key press for each of the given keys.
+func (h *harness) keys(keys ...gruid.Key) {
+	h.t.Helper()
+	for _, k := range keys {
+		h.send(gruid.MsgKeyDown{Key: k})
+	}
+}
+
+// mouse sends a mouse message at screen position p.
+func (h *harness) mouse(p gruid.Point, a gruid.MouseAction) {
+	h.t.Helper()
+	h.send(gruid.MsgMouse{Action: a, P: p})
+}
+
+// tick advances animations by n frames.
+func (h *harness) tick(n int) {
+	h.t.Helper()
+	for range n {
+		h.send(msgTick{})
+	}
+}
+
+// screen draws the model and returns the grid as text.
+func (h *harness) screen() string {
+	return gridText(h.m.Draw())
+}
+
+// line draws the model and returns line y of the grid.
+func (h *harness) line(y int) string {
+	return strings.Split(h.screen(), "\n")[y]
+}
+
+// ecs returns the ECS of the current game.
+func (h *harness) ecs() *ECS {
+	return h.m.game.ECS
+}
+
+// arena replaces the generated level with a single room spanning the whole
+// map, empty but for the player at p and their belongings, so that tests can
+// place exactly the entities they need.
+func (h *harness) arena(p gruid.Point) {
+	g := &h.m.game
+	g.Map.Grid.Fill(Wall)
+	g.Map.Grid.Slice(g.Map.Grid.Range().Shift(1, 1, -1, -1)).Fill(Floor)
+	carried := map[Entity]bool{}
+	for _, it := range g.PlayerInventory().items {
+		carried[it] = true
+	}
+	for _, e := range g.ECS.EntitiesWith() {
+		if e != 0 && !carried[e] {
+			g.ECS.Delete(e)
+		}
+	}
+	g.ECS.AddComponent(0, Position{p})
+	g.Log = nil
+	g.ECS.FOVSystem.Update(0)
+	g.ECS.LightingSystem.UpdateLighting()
+}
+
+// playerPos returns the position of the player, failing the test if it has
+// none.
+func (h *harness) playerPos() gruid.Point {
+	h.t.Helper()
+	pos, ok := Get[Position](h.ecs(), 0)
+	if !ok {
+		h.t.Fatal("player has no position")
+	}
+	return pos.Point
+}
+
+// logged returns true if some log entry contains s.
+func (h *harness) logged(s string) bool {
+	for _, e := range h.m.game.Log {
+		if strings.Contains(e.Text, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+// A driver that does not display anything, for tests and for scripted or
+// replayed runs in environments without a screen.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+// HeadlessDriver implements gruid.Driver without any display. Frames flushed
+// by the application are applied to an in-memory grid, which can be inspected
+// with Grid or Text. Input messages are fed with Send, or read from Input,
+// one key per line, if it is set.
+type HeadlessDriver struct {
+	Input  io.Reader // Optional source of keys, one per line.
+	Output io.Writer // Optional destination of the last frame, written on Close.
+
+	mu     sync.Mutex
+	grid   gruid.Grid
+	msgs   chan gruid.Msg
+	frames int
+}
+
+// NewHeadlessDriver returns a headless driver for a w x h grid.
+func NewHeadlessDriver(w, h int) *HeadlessDriver {
+	return &HeadlessDriver{
+		grid: gruid.NewGrid(w, h),
+		msgs: make(chan gruid.Msg, 64),
+	}
+}
+
+func (d *HeadlessDriver) Init() error {
+	if d.Input != nil {
+		go d.readInput()
+	}
+	return nil
+}
+
+// readInput sends every line of Input as a key press. Quitting is requested
+// once the input is exhausted.
+func (d *HeadlessDriver) readInput() {
+	sc := bufio.NewScanner(d.Input)
+	for sc.Scan() {
+		key := gruid.Key(sc.Text())
+		switch key {
+		case "Escape":
+			key = gruid.KeyEscape
+		case "Enter":
+			key = gruid.KeyEnter
+		case "Space":
+			key = gruid.KeySpace
+		}
+		d.Send(gruid.MsgKeyDown{Key: key})
+	}
+	d.Send(gruid.MsgQuit{})
+}
+
+// Send queues an input message, to be delivered to the application.
+func (d *HeadlessDriver) Send(msg gruid.Msg) {
+	d.msgs <- msg
+}
+
+func (d *HeadlessDriver) PollMsgs(ctx context.Context, ch chan<- gruid.Msg) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-d.msgs:
+			select {
+			case ch <- msg:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func (d *HeadlessDriver) Flush(fr gruid.Frame) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, fc := range fr.Cells {
+		d.grid.Set(fc.P, fc.Cell)
+	}
+	d.frames++
+}
+
+func (d *HeadlessDriver) Close() {
+	if d.Output != nil {
+		fmt.Fprint(d.Output, d.Text())
+	}
+}
+
+// Frames returns the number of frames flushed so far.
+func (d *HeadlessDriver) Frames() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.frames
+}
+
+// Grid returns a copy of the grid, as drawn by the last flushed frame.
+func (d *HeadlessDriver) Grid() gruid.Grid {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	gd := gruid.NewGrid(d.grid.Size().X, d.grid.Size().Y)
+	gd.Copy(d.grid)
+	return gd
+}
+
+// Text returns the runes of the last flushed frame, one line per row.
+func (d *HeadlessDriver) Text() string {
+	return gridText(d.Grid())
+}
+
+// gridText returns the runes of gd, one line per row, without trailing
+// spaces.
+func gridText(gd gruid.Grid) string {
+	var sb strings.Builder
+	size := gd.Size()
+	for y := 0; y < size.Y; y++ {
+		var line strings.Builder
+		for x := 0; x < size.X; x++ {
+			r := gd.At(gruid.Point{X: x, Y: y}).Rune
+			if r == 0 {
+				r = ' '
+			}
+			line.WriteRune(r)
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " "))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
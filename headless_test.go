@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+func TestHeadlessApp(t *testing.T) {
+	dr := NewHeadlessDriver(UIWidth, UIHeight)
+	m := NewModel(gruid.NewGrid(UIWidth, UIHeight), config{Seed: 1})
+	app := gruid.NewApp(gruid.AppConfig{Model: m, Driver: dr})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- app.Start(ctx) }()
+	dr.Send(gruid.MsgKeyDown{Key: "."})
+	dr.Send(gruid.MsgKeyDown{Key: "q"})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if dr.Frames() == 0 {
+		t.Fatal("no frame flushed")
+	}
+	text := dr.Text()
+	if !strings.Contains(text, "@") || !strings.Contains(text, "Seed 1  Turn 1") {
+		t.Errorf("unexpected last frame:\n%s", text)
+	}
+}
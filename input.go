@@ -1,8 +1,6 @@
 package main
 
 import (
-	"fmt"
-
 	"codeberg.org/anaseto/gruid"
 	"github.com/k0kubun/pp/v3"
 )
@@ -51,9 +49,17 @@ func (m *model) updateMsgKeyDown(msg gruid.MsgKeyDown) {
 		m.action = action{Type: ActionPickup}
 	case "x":
 		m.action = action{Type: ActionExamine}
+	case ">":
+		m.action = action{Type: ActionDescend}
+	case "<":
+		m.action = action{Type: ActionAscend}
+	case "o":
+		m.action = action{Type: ActionOrders}
+	case "t":
+		m.action = action{Type: ActionThrow}
 
 	// Debug actions
-	case "t":
+	case "T":
 		pp.Print(m.game.ECS.GetComponentsFor(0))
 	case `\`:
 		m.debugRevealAll = !m.debugRevealAll
@@ -107,7 +113,8 @@ func (m *model) updateTargeting(msg gruid.Msg) {
 			if m.mode == modeExamination {
 				break
 			}
-			m.activateTarget(p)
+			m.confirmTarget(p)
+			return
 
 		case gruid.KeyEscape, "q":
 			m.mode = modeNormal
@@ -127,10 +134,17 @@ func (m *model) updateTargeting(msg gruid.Msg) {
 	case gruid.MsgMouse:
 		switch msg.Action {
 		case gruid.MouseMove:
-			m.target.pos = msg.P.Shift(-1, -1)
+			// The cursor stays on the map when the mouse leaves it.
+			m.target.pos = m.clampToMap(msg.P.Shift(-1, -1))
 
 		case gruid.MouseMain:
-			fmt.Printf("Mouse click at: %v\n", msg.P)
+			// Clicking a tile targets it, as moving the cursor there
+			// and pressing enter would.
+			q := msg.P.Shift(-1, -1)
+			if m.mode == modeTargeting && q.In(m.game.Map.Grid.Range()) {
+				m.confirmTarget(q)
+				return
+			}
 		}
 	}
 
@@ -141,7 +155,34 @@ func (m *model) updateTargeting(msg gruid.Msg) {
 		if m.target != nil {
 			p, _ := m.game.ECS.GetComponent(0, Position{})
 			pos := p.(Position)
-			m.target.path = m.pr.JPSPath(m.target.path, pos.Point, m.target.pos, m.game.Pathable, true)
+			if m.target.rng > 0 {
+				m.target.path = m.game.ECS.flightLine(pos.Point, m.target.pos, m.target.rng)
+			} else {
+				m.target.path = m.pr.JPSPath(m.target.path, pos.Point, m.target.pos, m.game.Pathable, true)
+			}
 		}
 	}
 }
+
+// clampToMap returns the position of the map closest to p.
+func (m *model) clampToMap(p gruid.Point) gruid.Point {
+	rg := m.game.Map.Grid.Range()
+	p.X = min(max(p.X, rg.Min.X), rg.Max.X-1)
+	p.Y = min(max(p.Y, rg.Min.Y), rg.Max.Y-1)
+	return p
+}
+
+// confirmTarget ends targeting, acting on position p according to what was
+// being targeted.
+func (m *model) confirmTarget(p gruid.Point) {
+	switch {
+	case m.target.order:
+		m.action = action{Type: ActionOrder, Order: OrderAttack, Target: p}
+	case m.target.throw:
+		m.action = action{Type: ActionThrowItem, Key: m.target.key, Target: p}
+	default:
+		m.action = action{Type: ActionTarget, Key: m.target.key, Target: p}
+	}
+	m.mode = modeNormal
+	m.target = nil
+}
@@ -3,10 +3,12 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"sort"
 
 	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/paths"
 	"codeberg.org/anaseto/gruid/ui"
 )
 
@@ -22,7 +24,7 @@ func sortedInventoryKeys(inv Inventory) []rune {
 
 func (m *model) OpenInventory(title string) {
 	// Build list of entries in player inventory.
-	inv := GetComponent[Inventory](m.game.ECS, 0)
+	inv := m.game.PlayerInventory()
 	entries := []ui.MenuEntry{}
 	for _, k := range sortedInventoryKeys(inv) {
 		it := inv.items[k]
@@ -30,6 +32,11 @@ func (m *model) OpenInventory(title string) {
 		renderable := GetComponent[Renderable](m.game.ECS, it)
 		glyph := renderable.cell.Rune
 		fg := renderable.cell.Style.Fg
+		if m.game.ECS.HasComponent(it, Equipped{}) {
+			_, _, state := wearVerbs(GetComponent[Equippable](m.game.ECS, it).slot)
+			name += " (" + state + ")"
+		}
+		name += m.game.ECS.chargesLabel(it)
 		stt := ui.Text("").WithMarkup('k', gruid.Style{}.WithFg(fg))
 		entries = append(entries, ui.MenuEntry{
 			Text: stt.WithText(string(k) + " - @k" + string(glyph) + "@N " + name),
@@ -60,45 +67,91 @@ func (m *model) updateInventory(msg gruid.Msg) {
 		inv := m.game.PlayerInventory()
 		key := sortedInventoryKeys(inv)[m.inventory.Active()]
 		itemid := inv.items[key]
-		var err error
 		switch m.mode {
 		case modeInventoryDrop:
-			err = m.game.InventoryDrop(0, key)
+			m.action = action{Type: ActionDropItem, Key: key}
+		case modeInventoryThrow:
+			radius := 0
+			if m.game.ECS.HasComponent(itemid, Shatters{}) {
+				radius = m.game.ECS.radiusOf(itemid)
+			}
+			m.target = &targeting{
+				pos:    m.game.PlayerPosition().Shift(1, 1),
+				radius: radius,
+				itemid: itemid,
+				key:    key,
+				rng:    ThrowRange,
+				throw:  true,
+			}
+			m.mode = modeTargeting
+			return
 		case modeInventoryActivate:
 			// Check whether the given item has a ranged component
 			if m.game.ECS.HasComponent(itemid, Ranged{}) {
+				if !m.game.ECS.charged(itemid) {
+					m.game.Logf("The %s has no charges left.", ColorLogSpecial, GetComponent[Name](m.game.ECS, itemid).string)
+					m.mode = modeNormal
+					return
+				}
 				p := m.game.PlayerPosition()
 				m.target = &targeting{
 					pos:    p.Shift(1, 1),
-					radius: 1,
+					radius: m.game.ECS.radiusOf(itemid),
 					itemid: itemid,
+					key:    key,
+				}
+				if m.game.ECS.HasComponent(itemid, Bolt{}) || m.game.ECS.HasComponent(itemid, Blink{}) {
+					m.target.rng = GetComponent[Ranged](m.game.ECS, itemid).Range
 				}
 				m.mode = modeTargeting
 				return
 			}
-			err = m.game.InventoryActivate(0, key)
-		}
-		if err != nil {
-			m.game.Logf(err.Error(), ColorLogSpecial)
+			switch {
+			case m.game.ECS.HasComponent(itemid, Equipped{}):
+				m.action = action{Type: ActionUnequip, Key: key}
+			case m.game.ECS.HasComponent(itemid, Equippable{}):
+				m.action = action{Type: ActionEquip, Key: key}
+			default:
+				m.action = action{Type: ActionUseItem, Key: key}
+			}
 		}
-		m.game.ECS.Update()
 		m.mode = modeNormal
 	}
 }
 
-func (m *model) activateTarget(p gruid.Point) {
-	log.Println("Activating target at point p!")
-	log.Println(p)
-	// Check if there is an entity here capable of taking damage
-	itemid := m.target.itemid
-	itemdmg := GetComponent[Damage](m.game.ECS, itemid).int
-	if entities := m.game.ECS.EntitiesAtPWith(p, Health{}); len(entities) > 0 {
-		for _, e := range entities {
-			m.game.ECS.AddComponent(e, DamageEffect{0, itemdmg})
+// activateTarget uses the ranged item in the player's inventory slot key on
+// position p: wands and staffs fire their bolt at it, or blink toward it, and
+// other items affect everything in their area of effect around it.
+func (m *model) activateTarget(key rune, p gruid.Point) {
+	itemid, ok := m.game.PlayerInventory().items[key]
+	if !ok {
+		return
+	}
+	if !m.game.ECS.charged(itemid) {
+		m.game.Logf("The %s has no charges left.", ColorLogSpecial, GetComponent[Name](m.game.ECS, itemid).string)
+		return
+	}
+	m.game.ECS.useCharge(itemid)
+	switch {
+	case m.game.ECS.HasComponent(itemid, Blink{}):
+		m.game.ECS.blink(0, itemid, p)
+	case m.game.ECS.HasComponent(itemid, Bolt{}):
+		m.game.ECS.zap(0, itemid, p)
+	case m.game.ECS.HasComponent(itemid, Charming{}):
+		area := m.game.ECS.blastArea(p, m.game.ECS.radiusOf(itemid))
+		charmed := false
+		for _, q := range area {
+			for _, e := range m.game.ECS.EntitiesAtPWith(q, AI{}) {
+				charmed = m.game.Recruit(e) || charmed
+			}
 		}
+		if !charmed {
+			m.game.Logf("Nothing happens.", ColorLogSpecial)
+		}
+		m.game.ECS.queueBurst(itemid, p, area)
+	default:
+		m.game.ECS.explode(0, itemid, p)
 	}
-	m.target = nil
-	m.mode = modeNormal
 	// Remove item from inventory and world
 	if m.game.ECS.HasComponent(itemid, Consumable{}) {
 		inv := m.game.PlayerInventory()
@@ -106,47 +159,205 @@ func (m *model) activateTarget(p gruid.Point) {
 		m.game.ECS.AddComponent(0, inv)
 		m.game.ECS.Delete(itemid)
 	}
+	m.game.ECS.Spend(0, CostUse)
 	m.game.ECS.Update()
 }
 
 const ErrNoShow = "ErrNoShow"
 
-// TODO Better log messages
-func (g *game) InventoryActivate(entity int, key rune) error {
-	inventory := GetComponent[Inventory](g.ECS, entity)
-	item_id := inventory.items[key]
-	item_name := GetComponent[Name](g.ECS, item_id).string
-	g.Logf("You use the %s.", ColorLogSpecial, item_name)
-	// Item can provide healing. Apply healing.
-	if g.ECS.HasComponent(item_id, Healing{}) {
-		health := GetComponent[Health](g.ECS, entity)
-		healing := GetComponent[Healing](g.ECS, item_id)
-		health.hp += healing.amount
-		if health.hp > health.maxhp {
-			health.hp = health.maxhp
+// ErrNoItem is returned for inventory letters that do not hold any item, as
+// may happen when replaying a recording against a diverging game.
+var ErrNoItem = errors.New("You have no such item.")
+
+// report logs what e just did, if the player can see it. you is the message
+// for the player; they the one for other entities, after their name.
+func (ecs *ECS) report(e Entity, you, they string, args ...any) {
+	if e == 0 {
+		ecs.Create(LogEntry{Text: fmt.Sprintf(you, args...), Color: ColorLogSpecial})
+		return
+	}
+	pos, ok := Get[Position](ecs, e)
+	if !ok || !ecs.Map.VisibleNow[ecs.Map.idx(pos.Point)] {
+		return
+	}
+	name := GetComponent[Name](ecs, e).string
+	ecs.Create(LogEntry{Text: fmt.Sprintf("The %s "+they, append([]any{name}, args...)...), Color: ColorLogSpecial})
+}
+
+// Pickup makes e pick up the collectible items at its position, as long as
+// it has room for them. Monsters only take the items they want (see
+// wantsItem). It returns false if it picked up nothing.
+func (ecs *ECS) Pickup(e Entity) bool {
+	inv, ok := Get[Inventory](ecs, e)
+	if !ok {
+		return false // e.g. the player is dead.
+	}
+	if ecs.HasStatus(e, StatusLevitating) {
+		ecs.report(e, "You cannot reach the floor.", "cannot reach the floor.")
+		return false
+	}
+	pos := GetComponent[Position](ecs, e).Point
+	inv.removeStale(ecs)
+	picked := false
+	for _, it := range ecs.EntitiesAtPWith(pos, Collectible{}) {
+		if e != 0 && !ecs.wantsItem(it) {
+			continue // Monsters leave corpses behind.
 		}
-		g.ECS.AddComponent(entity, health)
+		k := inv.nextKey()
+		if k == 0 {
+			ecs.report(e, "You cannot carry any more.", "cannot carry any more.")
+			break
+		}
+		// Place a reference to the item in e's inventory and remove its
+		// Position component.
+		picked = true
+		name := GetComponent[Name](ecs, it).string
+		ecs.report(e, "You pick up the %s.", "picks up the %s.", name)
+		inv.items[k] = it
+		ecs.RemoveComponent(it, Position{})
+	}
+	ecs.AddComponent(e, inv)
+	if picked {
+		ecs.Spend(e, CostPickup)
 	}
+	return picked
+}
+
+// UseItem makes e use the item in its inventory slot key.
+func (ecs *ECS) UseItem(e Entity, key rune) error {
+	inventory := GetComponent[Inventory](ecs, e)
+	item_id, ok := inventory.items[key]
+	if !ok || !ecs.Alive(item_id) {
+		return ErrNoItem
+	}
+	item_name := GetComponent[Name](ecs, item_id).string
+	ecs.report(e, "You use the %s.", "uses the %s.", item_name)
+	if pos, ok := Get[Position](ecs, e); ok && e == 0 {
+		ecs.MakeNoise(pos.Point, NoiseUse)
+	}
+	// Item can provide healing, or inflict a status. Apply them.
+	ecs.affect(e, item_id)
 	// Item was consumable, so we delete from inventory.
-	if g.ECS.HasComponent(item_id, Consumable{}) {
+	if ecs.HasComponent(item_id, Consumable{}) {
 		delete(inventory.items, key)
-		g.ECS.AddComponent(entity, inventory)
-		g.ECS.Delete(item_id)
+		ecs.AddComponent(e, inventory)
+		ecs.Delete(item_id)
 	}
+	ecs.Spend(e, CostUse)
 	return nil
 }
 
-func (g *game) InventoryDrop(entity int, key rune) error {
-	inventory := GetComponent[Inventory](g.ECS, entity)
-	item_id := inventory.items[key]
-	item_name := GetComponent[Name](g.ECS, item_id).string
-	prefix := "You drop the "
-	g.Logf("%s %s.", ColorLogSpecial, prefix, item_name)
+// DropItem makes e drop the item in its inventory slot key where it stands.
+func (ecs *ECS) DropItem(e Entity, key rune) error {
+	inventory := GetComponent[Inventory](ecs, e)
+	item_id, ok := inventory.items[key]
+	if !ok || !ecs.Alive(item_id) {
+		return ErrNoItem
+	}
+	item_name := GetComponent[Name](ecs, item_id).string
+	ecs.report(e, "You drop the %s.", "drops the %s.", item_name)
 	// Remove item from inventory.
 	delete(inventory.items, key)
-	g.ECS.AddComponent(entity, inventory)
-	pos := GetComponent[Position](g.ECS, entity).Point
+	ecs.RemoveComponent(item_id, Equipped{})
+	ecs.AddComponent(e, inventory)
+	pos := GetComponent[Position](ecs, e).Point
 	// Add Position component back to the item.
-	g.ECS.AddComponent(item_id, Position{pos})
+	ecs.AddComponent(item_id, Position{pos})
+	ecs.Spend(e, CostDrop)
 	return nil
 }
+
+// wantsItem returns true if mobs with an inventory care to pick up it.
+// Corpses are left alone.
+func (ecs *ECS) wantsItem(it Entity) bool {
+	return ecs.HasComponents(it, Collectible{}, Position{}) && !ecs.HasComponent(it, Dead{})
+}
+
+// healingItem returns the inventory letter of a healing item carried by e.
+func (ecs *ECS) healingItem(e Entity) (rune, bool) {
+	inv, ok := Get[Inventory](ecs, e)
+	if !ok {
+		return 0, false
+	}
+	inv.removeStale(ecs)
+	for _, k := range sortedInventoryKeys(inv) {
+		if ecs.HasComponent(inv.items[k], Healing{}) {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// hasRoom returns true if e has an inventory with some room left.
+func (ecs *ECS) hasRoom(e Entity) bool {
+	inv, ok := Get[Inventory](ecs, e)
+	if !ok {
+		return false
+	}
+	inv.removeStale(ecs)
+	return inv.nextKey() != 0
+}
+
+func scoreQuaff(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if _, ok := s.ecs.healingItem(e); ok && s.ecs.wounded(e) {
+		return 900
+	}
+	return 0
+}
+
+func actQuaff(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	k, _ := s.ecs.healingItem(e)
+	s.ecs.AddComponent(e, Action{action: ActionUseItem, key: k})
+}
+
+func scorePickup(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if ai.state != CSWandering || !s.ecs.hasRoom(e) {
+		return 0
+	}
+	for _, it := range s.ecs.EntitiesAtPWith(p, Collectible{}) {
+		if s.ecs.wantsItem(it) {
+			return 400
+		}
+	}
+	return 0
+}
+
+func actPickup(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	s.ecs.AddComponent(e, Action{action: ActionPickup})
+}
+
+// nearestItem returns the position of the closest item e perceives and would
+// like to pick up.
+func (s *AISystem) nearestItem(e Entity, p gruid.Point) (gruid.Point, bool) {
+	per, ok := Get[Perception](s.ecs, e)
+	if !ok {
+		return gruid.Point{}, false
+	}
+	var q gruid.Point
+	best := -1
+	for _, it := range per.perceived {
+		if !s.ecs.wantsItem(it) {
+			continue
+		}
+		ip := GetComponent[Position](s.ecs, it).Point
+		if d := paths.DistanceChebyshev(p, ip); best < 0 || d < best {
+			q, best = ip, d
+		}
+	}
+	return q, best >= 0
+}
+
+func scoreFetch(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if ai.state != CSWandering || !s.ecs.hasRoom(e) {
+		return 0
+	}
+	if _, ok := s.nearestItem(e, p); !ok {
+		return 0
+	}
+	return 300
+}
+
+func actFetch(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	q, _ := s.nearestItem(e, p)
+	s.moveTo(e, ai, p, q)
+}
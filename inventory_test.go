@@ -0,0 +1,122 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+func TestPickupDrop(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	potion := h.m.game.NewHealthPotion(origin)
+	h.keys("g")
+	if Has[Position](h.ecs(), potion) {
+		t.Fatal("picked up potion still has a position")
+	}
+	// Slot a holds the player's sword.
+	if got := h.m.game.PlayerInventory().items['b']; got != potion {
+		t.Fatalf("inventory slot b holds %v, want %v", got, potion)
+	}
+	h.keys("l", "d")
+	if h.m.mode != modeInventoryDrop {
+		t.Fatalf("mode %v after d, want %v", h.m.mode, modeInventoryDrop)
+	}
+	if !strings.Contains(h.screen(), "health potion") {
+		t.Errorf("drop menu does not list the potion:\n%s", h.screen())
+	}
+	h.keys("b")
+	if h.m.mode != modeNormal {
+		t.Errorf("mode %v after dropping, want %v", h.m.mode, modeNormal)
+	}
+	pos, ok := Get[Position](h.ecs(), potion)
+	if !ok || pos.Point != origin.Shift(1, 0) {
+		t.Errorf("dropped potion at %v (%v), want %v", pos.Point, ok, origin.Shift(1, 0))
+	}
+	if len(h.m.game.PlayerInventory().items) != 1 {
+		t.Errorf("potion still in inventory after drop")
+	}
+}
+
+func TestQuaff(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	h.m.game.NewHealthPotion(origin)
+	h.ecs().AddComponent(0, Health{hp: 10, maxhp: 18})
+	h.keys("g", "i", "b")
+	if got := GetComponent[Health](h.ecs(), 0).hp; got != 15 {
+		t.Errorf("player hp %d, want 15", got)
+	}
+	if len(h.m.game.PlayerInventory().items) != 1 {
+		t.Errorf("potion not consumed")
+	}
+}
+
+func TestMonsterItems(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	// Out of the player's sight, a goblin walks to a potion and picks it up,
+	// leaving the corpse lying there.
+	goblin := g.NewGoblin(gruid.Point{X: 40, Y: 10})
+	corpse := g.NewCorpse(gruid.Point{X: 43, Y: 12})
+	potion := g.NewHealthPotion(gruid.Point{X: 43, Y: 12})
+	for range 5 {
+		h.keys(".")
+	}
+	inv := GetComponent[Inventory](g.ECS, goblin)
+	if len(inv.items) != 1 || g.ECS.HasComponent(potion, Position{}) {
+		t.Fatalf("goblin did not pick up the potion: %v", inv.items)
+	}
+	if !g.ECS.HasComponent(corpse, Position{}) {
+		t.Errorf("goblin picked up the corpse")
+	}
+	// Wounded, it drinks it.
+	g.ECS.AddComponent(goblin, Health{hp: 2, maxhp: 10})
+	h.keys(".")
+	if hp := GetComponent[Health](g.ECS, goblin).hp; hp <= 2 || g.ECS.Alive(potion) {
+		t.Errorf("goblin at %d hp, potion alive %v", hp, g.ECS.Alive(potion))
+	}
+	// On death, it drops what it carries.
+	scroll := g.NewScroll(gruid.Point{X: 1, Y: 1})
+	g.ECS.RemoveComponent(scroll, Position{})
+	g.ECS.AddComponent(goblin, Inventory{items: map[rune]Entity{'a': scroll}})
+	g.ECS.AddComponent(goblin, Health{hp: 0, maxhp: 10})
+	pos := GetComponent[Position](g.ECS, goblin).Point
+	h.keys(".")
+	if p, ok := Get[Position](g.ECS, scroll); !ok || p.Point != pos {
+		t.Errorf("scroll not dropped at %v", pos)
+	}
+}
+
+// TestDropOrder checks that a dead monster drops its items in the order of
+// its inventory, so that picking them up always gives the same letters.
+func TestDropOrder(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	goblin := g.NewGoblin(origin.Shift(1, 0))
+	g.ECS.RemoveComponent(goblin, AI{})
+	p := gruid.Point{X: 1, Y: 1}
+	carried := []Entity{g.NewDagger(p), g.NewLantern(p), g.NewScroll(p), g.NewSword(p), g.NewHealthPotion(p)}
+	inv := Inventory{items: map[rune]Entity{}}
+	for i, it := range carried {
+		g.ECS.RemoveComponent(it, Position{})
+		inv.items['a'+rune(i)] = it
+	}
+	g.ECS.AddComponent(goblin, inv)
+	g.ECS.AddComponent(goblin, Health{hp: 0, maxhp: 10})
+	h.keys(".", "l", "g")
+	var picked []Entity
+	pinv := g.PlayerInventory()
+	for _, k := range sortedInventoryKeys(pinv) {
+		if slices.Contains(carried, pinv.items[k]) {
+			picked = append(picked, pinv.items[k])
+		}
+	}
+	if !slices.Equal(picked, carried) {
+		t.Errorf("picked up %v, want %v in that order", picked, carried)
+	}
+}
@@ -0,0 +1,159 @@
+// Levels of the dungeon. Every level has its own map and ECS, generated from
+// the run's seed the first time it is visited, and kept as is while the
+// player is elsewhere. The player is entity 0 on every level: its components
+// and the items it carries move along from one level to the next, as do the
+// followers close enough to follow them.
+
+package main
+
+import (
+	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/paths"
+)
+
+// level holds the state of one level of the dungeon.
+type level struct {
+	Map *Map
+	ECS *ECS
+}
+
+// levelSeed returns the seed of the map at the given depth. The first level
+// is generated from the run's seed itself.
+func levelSeed(seed uint64, depth int) uint64 {
+	return seed + uint64(depth-1)*0x9e3779b97f4a7c15
+}
+
+// newLevel generates the level at the given depth, and makes it the current
+// one. Entity 0 is reserved for the player: it only holds the position of
+// the level's entry, so that nothing spawns there.
+func (g *game) newLevel(depth int) {
+	g.Map = NewMap(gruid.Point{X: MapWidth, Y: MapHeight}, levelSeed(g.Seed, depth))
+	g.ECS = NewECS()
+	g.ECS.Map = g.Map
+	entry := g.FreeFloorTile()
+	g.ECS.Create(Position{entry}, ObstructsMovement{})
+	if depth > 1 {
+		g.NewStairs(entry, false)
+	}
+	g.NewStairs(g.FreeFloorTile(), true)
+	g.SpawnTorches()
+	g.SpawnItems(depth)
+	g.SpawnEnemies(depth)
+	g.SpawnGrass()
+	g.ECS.LightingSystem.BakeTorchLighting()
+	g.Levels = append(g.Levels, &level{Map: g.Map, ECS: g.ECS})
+}
+
+// TakeStairs moves the player down (or up) the stairs they stand on. It
+// returns false, and logs why, if there are no such stairs.
+func (g *game) TakeStairs(down bool) bool {
+	if g.ECS.PlayerDead() {
+		return false
+	}
+	if _, ok := g.stairsAt(g.PlayerPosition(), down); !ok {
+		if down {
+			g.Logf("There are no stairs down here.", ColorLogSpecial)
+		} else {
+			g.Logf("There are no stairs up here.", ColorLogSpecial)
+		}
+		return false
+	}
+	if down && g.ECS.HasStatus(0, StatusLevitating) {
+		g.Logf("You float above the stairs.", ColorLogSpecial)
+		return false
+	}
+	g.ECS.Spend(0, CostMove)
+	if down {
+		g.changeLevel(g.Depth + 1)
+		g.Logf("You descend to depth %d.", ColorLogSpecial, g.Depth)
+	} else {
+		g.changeLevel(g.Depth - 1)
+		g.Logf("You climb up to depth %d.", ColorLogSpecial, g.Depth)
+	}
+	return true
+}
+
+// stairsAt returns the stairs at p going in the given direction, if any.
+func (g *game) stairsAt(p gruid.Point, down bool) (Entity, bool) {
+	for _, e := range g.ECS.EntitiesAtPWith(p, Stairs{}) {
+		if GetComponent[Stairs](g.ECS, e).down == down {
+			return e, true
+		}
+	}
+	return 0, false
+}
+
+// changeLevel moves the player, along with their inventory, to the level at
+// the given depth, generating it if needed. The player arrives on the stairs
+// leading back to the level they come from.
+func (g *game) changeLevel(depth int) {
+	from := g.ECS
+	down := depth > g.Depth
+	if depth > len(g.Levels) {
+		g.newLevel(depth)
+	} else {
+		lvl := g.Levels[depth-1]
+		g.Map, g.ECS = lvl.Map, lvl.ECS
+	}
+	g.Depth = depth
+	g.ECS.Turn = from.Turn
+	from.moveInventory(0, g.ECS)
+	old := GetComponent[Position](from, 0).Point
+	from.moveComponents(0, g.ECS, 0)
+	g.ECS.RemoveComponent(0, Bump{})
+	arrival := g.PlayerPosition()
+	for _, e := range g.ECS.EntitiesWith(Stairs{}) {
+		if GetComponent[Stairs](g.ECS, e).down != down {
+			arrival = GetComponent[Position](g.ECS, e).Point
+		}
+	}
+	g.ECS.AddComponent(0, Position{g.freeTileNear(arrival)})
+	g.bringFollowers(from, old)
+	g.ECS.Initialize()
+}
+
+// moveInventory recreates the items carried by e in dst, in preparation for
+// e moving there.
+func (ecs *ECS) moveInventory(e Entity, dst *ECS) {
+	inv, ok := Get[Inventory](ecs, e)
+	if !ok {
+		return
+	}
+	inv.removeStale(ecs)
+	// Items are recreated in inventory order, so that their entities in dst
+	// do not depend on map iteration order.
+	for _, k := range sortedInventoryKeys(inv) {
+		it := inv.items[k]
+		inv.items[k] = dst.Create()
+		ecs.moveComponents(it, dst, inv.items[k])
+		ecs.Delete(it)
+	}
+}
+
+// freeTileNear returns the walkable tile without blocking entities closest
+// to p, p itself if possible.
+func (g *game) freeTileNear(p gruid.Point) gruid.Point {
+	free := func(q gruid.Point) bool {
+		if !g.Map.Walkable(q) {
+			return false
+		}
+		for _, e := range g.ECS.EntitiesAtPWith(q, ObstructsMovement{}) {
+			if e != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	size := g.Map.Grid.Size()
+	for r := 0; r < max(size.X, size.Y); r++ {
+		for y := p.Y - r; y <= p.Y+r; y++ {
+			for x := p.X - r; x <= p.X+r; x++ {
+				q := gruid.Point{X: x, Y: y}
+				if paths.DistanceChebyshev(p, q) == r && free(q) {
+					return q
+				}
+			}
+		}
+	}
+	return p
+}
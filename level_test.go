@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStairs(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	g.NewStairs(origin, true)
+	potion := g.NewHealthPotion(origin)
+	goblin := g.NewGoblin(origin.Shift(5, 5))
+	g.ECS.RemoveComponent(goblin, AI{})
+	g.ECS.AddComponent(goblin, Health{hp: 3, maxhp: 10})
+	h.keys("<")
+	if !h.logged("There are no stairs up here.") || g.Depth != 1 {
+		t.Fatalf("went up from depth 1")
+	}
+	h.keys("g", ">")
+	if g.Depth != 2 || len(g.Levels) != 2 {
+		t.Fatalf("depth %d with %d levels after descending", g.Depth, len(g.Levels))
+	}
+	if _, ok := g.stairsAt(h.playerPos(), false); !ok {
+		t.Errorf("player did not arrive on the stairs up")
+	}
+	if !strings.Contains(h.line(UIHeight-1), "Depth 2") {
+		t.Errorf("status line %q does not show the depth", h.line(UIHeight-1))
+	}
+	// The potion came along, as a new entity of the new level.
+	it := g.PlayerInventory().items['b']
+	if GetComponent[Name](g.ECS, it).string != "health potion" {
+		t.Errorf("potion not carried to depth 2")
+	}
+	h.keys("<")
+	if g.Depth != 1 || h.playerPos() != origin {
+		t.Fatalf("depth %d at %v after climbing back", g.Depth, h.playerPos())
+	}
+	if g.ECS.Alive(potion) {
+		t.Errorf("carried potion left behind on depth 1")
+	}
+	if hp := GetComponent[Health](g.ECS, goblin).hp; hp != 3 {
+		t.Errorf("goblin hp %d after coming back, want 3", hp)
+	}
+	// All levels are saved.
+	path := t.TempDir() + "/save.json"
+	if err := g.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	var loaded game
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Depth != 1 || len(loaded.Levels) != 2 {
+		t.Errorf("loaded depth %d with %d levels", loaded.Depth, len(loaded.Levels))
+	}
+	if hp := GetComponent[Health](loaded.ECS, goblin).hp; hp != 3 {
+		t.Errorf("loaded goblin hp %d, want 3", hp)
+	}
+}
+
+// TestMoveInventory checks that carried items are recreated in inventory
+// order, so that the entities of a new level only depend on the seed.
+func TestMoveInventory(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	g.NewDagger(origin)
+	g.NewLantern(origin)
+	g.NewScroll(origin)
+	g.NewSword(origin)
+	g.NewHealthPotion(origin)
+	h.keys("g")
+	dst := NewECS()
+	g.ECS.moveInventory(0, dst)
+	inv := GetComponent[Inventory](g.ECS, 0)
+	if len(inv.items) < 5 {
+		t.Fatalf("%d items carried, want at least 5", len(inv.items))
+	}
+	last := -1
+	for _, k := range sortedInventoryKeys(inv) {
+		it := inv.items[k]
+		if !dst.Alive(it) || it.Index() <= last {
+			t.Fatalf("item %c recreated as %v after index %d", k, it, last)
+		}
+		last = it.Index()
+	}
+}
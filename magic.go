@@ -0,0 +1,112 @@
+// Wands and staffs. Both are aimed like other ranged items, and most of them
+// fire bolts along the line of fire; others carry their user along it. They
+// hold a few charges: staffs slowly regain theirs, while wands run out for
+// good.
+
+package main
+
+import (
+	"fmt"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+// charged returns true if item can be used, that is, if it has charges left
+// or does not need any.
+func (ecs *ECS) charged(item Entity) bool {
+	ch, ok := Get[Charges](ecs, item)
+	return !ok || ch.left > 0
+}
+
+// useCharge spends one of the charges of item, if it has any.
+func (ecs *ECS) useCharge(item Entity) {
+	ch, ok := Get[Charges](ecs, item)
+	if !ok || ch.left == 0 {
+		return
+	}
+	ch.left--
+	ecs.AddComponent(item, ch)
+}
+
+// chargesLabel returns how the inventory shows the charges of item, if it
+// has any.
+func (ecs *ECS) chargesLabel(item Entity) string {
+	ch, ok := Get[Charges](ecs, item)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" (%d/%d)", ch.left, ch.max)
+}
+
+// zap fires the bolt of item, used by e, toward target, and strikes what is
+// in its way.
+func (ecs *ECS) zap(e, item Entity, target gruid.Point) {
+	bolt := GetComponent[Bolt](ecs, item)
+	p := GetComponent[Position](ecs, e).Point
+	ecs.report(e, "You zap the %s.", "zaps the %s.", GetComponent[Name](ecs, item).string)
+	flight := []gruid.Point{}
+	for _, q := range ecs.flightLine(p, target, GetComponent[Ranged](ecs, item).Range) {
+		flight = append(flight, q)
+		victims := ecs.EntitiesAtPWith(q, Health{}, ObstructsMovement{})
+		for _, v := range victims {
+			ecs.strike(e, v, item, bolt.missile)
+		}
+		if len(victims) > 0 && !bolt.pierce {
+			break
+		}
+	}
+	fg := GetComponent[Renderable](ecs, item).cell.Style.Fg
+	ecs.Create(Projectile{from: p, path: flight, glyph: bolt.glyph, color: fg})
+	if e == 0 {
+		ecs.MakeNoise(p, NoiseUse)
+	}
+}
+
+// strike applies the effects of the bolt of item, used by e, to v. Bolts
+// never miss.
+func (ecs *ECS) strike(e, v, item Entity, missile string) {
+	if dmg, ok := Get[Damage](ecs, item); ok {
+		ecs.queueDamage(v, DamageEffect{source: e, amount: dmg.roll(ecs.Map.Rand), missile: missile})
+	}
+	ecs.affect(v, item)
+}
+
+// blink carries e toward target, up to the range of item, stopping before
+// anything in the way.
+func (ecs *ECS) blink(e, item Entity, target gruid.Point) {
+	p := GetComponent[Position](ecs, e).Point
+	flight := []gruid.Point{}
+	for _, q := range ecs.flightLine(p, target, GetComponent[Ranged](ecs, item).Range) {
+		if !ecs.NoBlockingEntityAt(q) {
+			break
+		}
+		flight = append(flight, q)
+	}
+	if len(flight) == 0 {
+		ecs.report(e, "You flicker in place.", "flickers in place.")
+		return
+	}
+	ecs.report(e, "You blink.", "blinks.")
+	ecs.AddComponent(e, Position{flight[len(flight)-1]})
+	fg := GetComponent[Renderable](ecs, item).cell.Style.Fg
+	ecs.Create(Projectile{from: p, path: flight, glyph: '*', color: fg})
+}
+
+type ChargeSystem struct {
+	ecs *ECS
+}
+
+// Update lets an item with a Charges{} component regain its charges over
+// time, if it is a staff.
+func (s *ChargeSystem) Update(e Entity) {
+	ch := GetComponent[Charges](s.ecs, e)
+	if ch.recharge == 0 || ch.left >= ch.max {
+		return
+	}
+	ch.timer++
+	if ch.timer >= ch.recharge {
+		ch.left++
+		ch.timer = 0
+	}
+	s.ecs.AddComponent(e, ch)
+}
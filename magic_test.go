@@ -0,0 +1,79 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+func TestWands(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	goblin := func(p gruid.Point) Entity {
+		e := g.NewGoblin(p)
+		g.ECS.RemoveComponent(e, AI{})
+		g.ECS.AddComponent(e, Health{hp: 30, maxhp: 30})
+		return e
+	}
+	first, second := goblin(origin.Shift(2, 0)), goblin(origin.Shift(4, 0))
+
+	// Staffs fire bolts along the line of fire, which is shown while aiming.
+	staff := g.NewStaffOfFirebolt(origin)
+	h.keys("g", "i", "b")
+	if h.m.mode != modeTargeting {
+		t.Fatalf("mode %v, want targeting", h.m.mode)
+	}
+	h.keys("l", "l", "l", "k")
+	if want := bresenham(origin, origin.Shift(4, 0), 4); !slices.Equal(h.m.target.path, want) {
+		t.Errorf("targeting path %v, want the line of fire %v", h.m.target.path, want)
+	}
+	h.keys(gruid.KeyEnter)
+	if GetComponent[Health](g.ECS, first).hp == 30 || !g.ECS.HasStatus(first, StatusBurning) {
+		t.Errorf("firebolt did not hurt and burn the first goblin")
+	}
+	if GetComponent[Health](g.ECS, second).hp != 30 {
+		t.Errorf("firebolt went through the first goblin")
+	}
+	if h.m.ianimation == nil {
+		t.Errorf("no bolt animation")
+	}
+	h.keys("i")
+	if !strings.Contains(h.screen(), "staff of firebolt (2/3)") {
+		t.Errorf("charges not shown in the inventory:\n%s", h.screen())
+	}
+	h.keys(gruid.KeyEscape)
+
+	// Staffs recharge over time, but wands do not.
+	for range 40 {
+		g.ECS.ChargeSystem.Update(staff)
+	}
+	if ch := GetComponent[Charges](g.ECS, staff); ch.left != 3 {
+		t.Errorf("staff at %d charges after recharging, want 3", ch.left)
+	}
+
+	// Lightning pierces through everything in its line.
+	wand := g.NewWandOfLightning(origin)
+	h.keys("g", "i", "c", "l", "l", "l", "k", gruid.KeyEnter)
+	if GetComponent[Health](g.ECS, second).hp == 30 {
+		t.Errorf("lightning did not reach the second goblin")
+	}
+	g.ECS.AddComponent(wand, Charges{left: 0, max: 4})
+	for range 100 {
+		g.ECS.ChargeSystem.Update(wand)
+	}
+	g.Log = nil
+	h.keys("i", "c")
+	if h.m.mode == modeTargeting || !h.logged("The wand of lightning has no charges left.") {
+		t.Errorf("empty wand used, mode %v, log %v", h.m.mode, g.Log)
+	}
+
+	// Blinking stops before anything in the way.
+	g.NewStaffOfBlinking(origin)
+	h.keys("g", "i", "d", "l", "l", "l", "k", gruid.KeyEnter)
+	if p := h.playerPos(); p != origin.Shift(1, 0) {
+		t.Errorf("player blinked to %v, want %v", p, origin.Shift(1, 0))
+	}
+}
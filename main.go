@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"time"
 
 	"codeberg.org/anaseto/gruid"
 )
@@ -15,13 +18,37 @@ const (
 	LogLines  = 5
 )
 
+// config holds the options given on the command line.
+type config struct {
+	Seed        uint64        // Seed of a new run. 0 resumes the saved game, if any, or picks a random seed.
+	SavePath    string        // Save file location; "" disables saving.
+	RecordPath  string        // Replay file written on quit; "" disables recording.
+	ReplayPath  string        // Replay file to play back instead of playing.
+	ReplayDelay time.Duration // Delay between replayed actions; 0 plays them all at once.
+}
+
 func main() {
+	cfg := config{SavePath: defaultSavePath()}
+	flag.Uint64Var(&cfg.Seed, "seed", 0, "start a new run from this seed instead of resuming")
+	flag.StringVar(&cfg.RecordPath, "record", userFile("last-replay.json"), "write a replay of the run to this file on quit")
+	flag.StringVar(&cfg.ReplayPath, "replay", "", "play back the given replay file")
+	flag.DurationVar(&cfg.ReplayDelay, "replay-delay", 100*time.Millisecond, "delay between replayed actions (0 plays them instantly)")
+	flag.Func("theme", "color theme: selenized, noir or sepia", func(name string) error {
+		t, ok := themeNames[name]
+		if !ok {
+			return fmt.Errorf("unknown theme %q", name)
+		}
+		theme = t
+		return nil
+	})
+	flag.Parse()
+
 	// Construct the drawgrid, and a new model.
 	gd := gruid.NewGrid(UIWidth, UIHeight)
-	m := NewModel(gd)
+	m := NewModel(gd, cfg)
 
 	// Instantiate new app. driver is generated in sdl.go, or in
-	// js.go if application is built with js flags (see README).
+	// js.go, tcell.go or stdio.go depending on build tags (see README).
 	app := gruid.NewApp(gruid.AppConfig{
 		Model:  m,
 		Driver: driver,
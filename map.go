@@ -15,13 +15,17 @@ const (
 
 // Map represents the rectangular grid of the game's level.
 type Map struct {
-	Grid         rl.Grid    // Gamemap.
-	Rand         *rand.Rand // Random number generator.
-	Explored     []bool     // Flat array [y*MapWidth+x]: tiles the player has ever seen.
-	LightMap     []float32  // Flat array [y*MapWidth+x]: per-tile light level (0.0–1.0), updated each turn.
-	BakedLightMap []float32 // Flat array [y*MapWidth+x]: pre-computed static torch lighting, written once.
-	VisibleNow   []bool     // Flat array [y*MapWidth+x]: tiles in player FOV this turn, updated each turn.
-	PR           *paths.PathRange
+	Grid          rl.Grid    // Gamemap.
+	Rand          *rand.Rand // Random number generator.
+	src           *rand.PCG  // Source of Rand, kept so its state can be saved.
+	Explored      []bool     // Flat array [y*MapWidth+x]: tiles the player has ever seen.
+	LightMap      []float32  // Flat array [y*MapWidth+x]: per-tile light level (0.0–1.0), updated each turn.
+	BakedLightMap []float32  // Flat array [y*MapWidth+x]: pre-computed static torch lighting, written once.
+	VisibleNow    []bool     // Flat array [y*MapWidth+x]: tiles in player FOV this turn, updated each turn.
+	PR            *paths.PathRange
+	PlayerMap     *DijkstraMap // Distance to the player.
+	SafetyMap     *DijkstraMap // Downhill leads away from the player.
+	UnexploredMap *DijkstraMap // Distance to tiles the player has not explored.
 }
 
 // idx converts a map point to a flat array index.
@@ -29,19 +33,31 @@ func (m *Map) idx(p gruid.Point) int {
 	return p.Y*MapWidth + p.X
 }
 
-func NewMap(size gruid.Point) *Map {
+// NewMap generates a new map. All of its randomness, including the rolls made
+// later on by the systems, is drawn from a generator seeded with seed.
+func NewMap(size gruid.Point, seed uint64) *Map {
+	m := newMap(size, rand.NewPCG(seed, seed))
+	m.Generate()
+	return m
+}
+
+// newMap allocates an all-wall map of the given size, drawing random numbers
+// from src. It does not generate any rooms.
+func newMap(size gruid.Point, src *rand.PCG) *Map {
 	n := size.X * size.Y
-	m := &Map{
+	return &Map{
 		Grid:          rl.NewGrid(size.X, size.Y),
-		Rand:          rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		Rand:          rand.New(src),
+		src:           src,
 		Explored:      make([]bool, n),
 		LightMap:      make([]float32, n),
 		BakedLightMap: make([]float32, n),
 		VisibleNow:    make([]bool, n),
 		PR:            paths.NewPathRange(gruid.NewRange(0, 0, size.X, size.Y)),
+		PlayerMap:     NewDijkstraMap(size),
+		SafetyMap:     NewDijkstraMap(size),
+		UnexploredMap: NewDijkstraMap(size),
 	}
-	m.Generate()
-	return m
 }
 
 func (m *Map) Walkable(p gruid.Point) bool {
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"log"
 	"sort"
 	"strings"
 	"time"
+
 	"codeberg.org/anaseto/gruid"
 	"codeberg.org/anaseto/gruid/paths"
 	"codeberg.org/anaseto/gruid/ui"
@@ -20,12 +22,18 @@ type model struct {
 	desc           *ui.Label        // Label for position description.
 	viewer         *ui.Pager        // Message's history viewer.
 	inventory      *ui.Menu         // Inventory menu.
+	orders         *ui.Menu         // Followers' orders menu.
 	pr             *paths.PathRange // Pathing algorithm.
 	target         *targeting       // Mouse position.
 	ianimation     *Animation       // Interruptible animation.
-	debugRevealAll  bool // Debug: reveal entire map.
-	debugAIPaths    bool // Debug: visualize AI entity paths.
-	mouseActive     bool // True once mouse has hovered over a visible tile.
+	savePath       string           // Save file location; "" disables saving.
+	recordPath     string           // Replay file written on quit; "" disables recording.
+	replay         *Replay          // Replay being played back, if any.
+	replayNext     int              // Index of the next replay action to play.
+	replayDelay    time.Duration    // Delay between replayed actions.
+	debugRevealAll bool             // Debug: reveal entire map.
+	debugAIPaths   bool             // Debug: visualize AI entity paths.
+	mouseActive    bool             // True once mouse has hovered over a visible tile.
 }
 
 // targeting describes information related to examination or selection of
@@ -33,8 +41,12 @@ type model struct {
 type targeting struct {
 	pos    gruid.Point   // The current position of the cursor.
 	path   []gruid.Point // The path to the current position.
-	itemid int           // The entity ID of the item being used/thrown/activated.
+	itemid Entity        // The entity of the item being used/thrown/activated.
+	key    rune          // The inventory letter of that item.
 	radius int           // Radius of the targeting area.
+	rng    int           // Range of the missile aimed, whose line of fire is then shown.
+	order  bool          // Choosing the target of an attack order.
+	throw  bool          // Choosing where to throw the item.
 }
 
 // mode describes distinct kinds of modes for the UI. It is used to send user
@@ -48,13 +60,16 @@ const (
 	modeMessageViewer                 // Currently viewing messages.
 	modeInventoryActivate             // Browsing inventory, in order to use an item.
 	modeInventoryDrop                 // Browsing inventory, in order to drop an item.
+	modeInventoryThrow                // Browsing inventory, in order to throw an item.
 	modeExamination                   // Keyboard map examination mode.
+	modeOrders                        // Choosing an order for followers.
 	modeTargeting
 )
 
-func NewModel(gd gruid.Grid) *model {
-	return &model{
+func NewModel(gd gruid.Grid, cfg config) *model {
+	m := &model{
 		grid:   gd,
+		game:   game{Seed: cfg.Seed},
 		log:    &ui.Label{},
 		status: &ui.Label{},
 		desc:   &ui.Label{},
@@ -62,8 +77,23 @@ func NewModel(gd gruid.Grid) *model {
 			Grid: gruid.NewGrid(UIWidth, UIHeight-1),
 			Box:  &ui.Box{},
 		}),
-		pr: paths.NewPathRange(gd.Range()),
+		pr:          paths.NewPathRange(gd.Range()),
+		savePath:    cfg.SavePath,
+		recordPath:  cfg.RecordPath,
+		replayDelay: cfg.ReplayDelay,
+	}
+	if cfg.ReplayPath != "" {
+		r, err := LoadReplay(cfg.ReplayPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Playing back a replay must not touch the saved game, nor the
+		// recording of the last run.
+		m.replay = r
+		m.savePath = ""
+		m.recordPath = ""
 	}
+	return m
 }
 
 type msgTick struct{}
@@ -89,13 +119,22 @@ func (m *model) Update(msg gruid.Msg) gruid.Effect {
 
 	m.action = action{}
 
+	// Closing the window saves the game, whatever the current mode.
+	if _, ok := msg.(gruid.MsgQuit); ok {
+		return m.quit()
+	}
+
+	if m.replay != nil {
+		return m.updateReplay(msg)
+	}
+
 	switch m.mode {
 
 	case modeNormal:
 		switch msg := msg.(type) {
 
 		case gruid.MsgInit:
-			m.game.Initialize()
+			m.resume()
 			return frameTicker()
 
 		case gruid.MsgKeyDown:
@@ -126,13 +165,14 @@ func (m *model) Update(msg gruid.Msg) gruid.Effect {
 		}
 		return nil
 
-	case modeInventoryActivate, modeInventoryDrop:
+	case modeInventoryActivate, modeInventoryDrop, modeInventoryThrow:
 		m.updateInventory(msg)
-		return nil
+
+	case modeOrders:
+		m.updateOrders(msg)
 
 	case modeTargeting, modeExamination:
 		m.updateTargeting(msg)
-		return nil
 
 	case modeEnd:
 		switch msg := msg.(type) {
@@ -140,7 +180,7 @@ func (m *model) Update(msg gruid.Msg) gruid.Effect {
 			switch msg.Key {
 			case "q", gruid.KeyEscape:
 				// You died: quit on "q" or "escape"
-				return gruid.End()
+				return m.quit()
 			case ".":
 				// Otherwise, allow player to continue watching sim.
 				m.updateMsgKeyDown(msg)
@@ -149,7 +189,10 @@ func (m *model) Update(msg gruid.Msg) gruid.Effect {
 		return nil
 	}
 
-	// Handle action (if any provided).
+	// Record the action for replays, and handle it (if any provided).
+	if m.action.recordable() {
+		m.game.History = append(m.game.History, m.action)
+	}
 	return m.handleAction()
 }
 
@@ -170,11 +213,17 @@ func (m *model) Draw() gruid.Grid {
 	}
 
 	// Render the inventory, if that's the mode we're in.
-	if m.mode == modeInventoryActivate || m.mode == modeInventoryDrop {
+	if m.mode == modeInventoryActivate || m.mode == modeInventoryDrop || m.mode == modeInventoryThrow {
 		m.grid.Copy(m.inventory.Draw())
 		return m.grid
 	}
 
+	// Render the orders menu, if that's the mode we're in.
+	if m.mode == modeOrders {
+		m.grid.Copy(m.orders.Draw())
+		return m.grid
+	}
+
 	///////////////////////////////////////////////////
 	// Otherwise, render the map, entities, and log. //
 	///////////////////////////////////////////////////
@@ -207,7 +256,7 @@ func (m *model) Draw() gruid.Grid {
 
 	// Collect entities to draw.
 	type tup struct {
-		entity int
+		entity Entity
 		order  renderOrder
 	}
 	entitiesToDraw := []tup{}
@@ -273,11 +322,20 @@ func lightColor(level float32) gruid.Color {
 	}
 }
 
-// DrawTarget draws the current position of the mouse.
+// DrawTarget draws the current position of the mouse. When aiming an item
+// with an area of effect, the area it would cover is highlighted too.
 func (m *model) DrawTarget(gd gruid.Grid) {
 	if m.target == nil {
 		return
 	}
+	if m.mode == modeTargeting && m.target.radius > 0 {
+		for _, p := range m.game.ECS.blastArea(m.target.pos, m.target.radius) {
+			if p.In(m.game.Map.Grid.Range()) && m.game.Map.Explored[m.game.Map.idx(p)] {
+				c := gd.At(p)
+				gd.Set(p, c.WithStyle(c.Style.WithBg(ColorTarget)))
+			}
+		}
+	}
 	for _, p := range m.target.path {
 		c := gd.At(p)
 		gd.Set(p, c.WithStyle(c.Style.WithAttrs(AttrReverse)))
@@ -321,8 +379,8 @@ func (m *model) DrawLog(gd gruid.Grid) {
 	}
 }
 
-// DrawStatus writes the HP on the bottom of the screen. If the player is dead,
-// displays "DEAD" in red.
+// DrawStatus writes the HP on the bottom of the screen, followed by the
+// player's statuses. If the player is dead, displays "DEAD" in red.
 func (m *model) DrawStatus(gd gruid.Grid) {
 	if m.game.ECS.PlayerDead() {
 		m.log.Content = ui.Text("  DEAD  ").WithStyle(gruid.Style{Fg: ColorBlood})
@@ -336,6 +394,19 @@ func (m *model) DrawStatus(gd gruid.Grid) {
 		m.log.Content = ui.Textf("HP: %d/%d", player_health.hp, player_health.maxhp).WithStyle(st)
 	}
 	m.log.Draw(gd)
+	// Then the icons of the player's statuses.
+	x := m.log.Content.Size().X
+	for _, id := range m.game.ECS.statusesOf(0) {
+		def := statusDefs[id]
+		m.log.Content = ui.Text(def.icon).WithStyle(gruid.Style{Fg: def.color})
+		m.log.Draw(gd.Slice(gd.Range().Columns(x+1, gd.Size().X)))
+		x += 1 + m.log.Content.Size().X
+	}
+	// Depth, then seed and turn, right-aligned, so that bug reports can
+	// point at them.
+	m.status.Content = ui.Textf("Depth %d  Seed %d  Turn %d", m.game.Depth, m.game.Seed, m.game.ECS.Turn)
+	w := m.status.Content.Size().X
+	m.status.Draw(gd.Slice(gd.Range().Columns(gd.Size().X-w, gd.Size().X)))
 }
 
 // DrawNames writes a "You see a [name]." line when the mouse hovers over a
@@ -407,9 +478,11 @@ func (m *model) DrawInterruptibleAnimation(gd gruid.Grid) {
 	if m.ianimation != nil {
 		anim := m.ianimation
 		for _, fc := range anim.frames[anim.index].framecells {
-			p := fc.p
-			r := fc.r
-			gd.Set(p, r.cell)
+			c := fc.r.cell
+			if fc.r.LacksBg() {
+				c.Style.Bg = gd.At(fc.p).Style.Bg
+			}
+			gd.Set(fc.p, c)
 		}
 	}
 }
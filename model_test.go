@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+func TestMovement(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []gruid.Key
+		want gruid.Point
+		turn int
+	}{
+		{"right", []gruid.Key{"l"}, origin.Shift(1, 0), 1},
+		{"arrows", []gruid.Key{gruid.KeyArrowDown, gruid.KeyArrowDown}, origin.Shift(0, 2), 2},
+		{"diagonal", []gruid.Key{"y", "u"}, origin.Shift(0, -2), 2},
+		{"wait", []gruid.Key{"."}, origin, 1},
+		// Bumping into a wall takes no time.
+		{"wall", []gruid.Key{"h", "h", "h", "h", "h", "h", "h", "h", "h", "h"}, gruid.Point{X: 1, Y: 10}, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, 1)
+			h.arena(origin)
+			h.keys(tt.keys...)
+			if got := h.playerPos(); got != tt.want {
+				t.Errorf("player at %v, want %v", got, tt.want)
+			}
+			if got := h.m.game.ECS.Turn; got != tt.turn {
+				t.Errorf("turn %d, want %d", got, tt.turn)
+			}
+			// The player is drawn shifted by the map's offset.
+			sp := tt.want.Shift(1, 1)
+			if r := []rune(h.line(sp.Y))[sp.X]; r != '@' {
+				t.Errorf("drew %q at player position", r)
+			}
+		})
+	}
+}
+
+func TestMouseDescription(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	h.m.game.NewGoblin(origin.Shift(2, 0))
+	h.mouse(origin.Shift(3, 1), gruid.MouseMove)
+	if !strings.Contains(h.screen(), "You see a goblin.") {
+		t.Errorf("hovering a goblin does not describe it:\n%s", h.screen())
+	}
+	// Frame ticks animate the screen, but do not play any turn.
+	h.tick(3)
+	if got := h.ecs().Turn; got != 0 {
+		t.Errorf("turn %d after ticks, want 0", got)
+	}
+}
+
+func TestMouseTargeting(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	dagger := g.NewDagger(origin)
+	h.keys("g", "t", "b")
+	// The map is drawn one tile in from the top left corner of the screen.
+	h.mouse(origin.Shift(4, 1), gruid.MouseMain)
+	if h.m.mode != modeNormal {
+		t.Errorf("mode %v after clicking a target, want normal", h.m.mode)
+	}
+	if pos, ok := Get[Position](g.ECS, dagger); !ok || pos.Point != origin.Shift(3, 0) {
+		t.Errorf("dagger thrown at %v, want the clicked %v", pos, origin.Shift(3, 0))
+	}
+}
@@ -0,0 +1,126 @@
+// Packs: groups of monsters spawned together around a leader, such as goblin
+// war parties. Members stay close to their leader while wandering, and as
+// soon as one of them spots an enemy, the whole pack goes after it.
+
+package main
+
+import (
+	"fmt"
+
+	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/paths"
+)
+
+// PackDistance is how far, in tiles, pack members wander from their leader,
+// and spawn from them.
+const PackDistance = 3
+
+// spawnPack turns the monster leader into the leader of a pack spawned around
+// it, and returns all the members of the pack, leader first.
+func (g *game) spawnPack(leader Entity, pk pack) []Entity {
+	group := []Entity{leader}
+	g.ECS.AddComponent(leader, Pack{leader: leader})
+	tiles := g.tilesNear(GetComponent[Position](g.ECS, leader).Point, PackDistance)
+	n := pk.min + g.Map.Rand.IntN(pk.max-pk.min+1)
+	for i := 0; i < n && len(tiles) > 0; i++ {
+		j := g.Map.Rand.IntN(len(tiles))
+		e := pk.member(g, tiles[j])
+		tiles = append(tiles[:j], tiles[j+1:]...)
+		g.ECS.AddComponent(e, Pack{leader: leader})
+		group = append(group, e)
+	}
+	return group
+}
+
+// tilesNear returns the free floor tiles that can be reached from p in at
+// most radius steps, so that they are in the same room as p, or just around
+// a corner.
+func (g *game) tilesNear(p gruid.Point, radius int) []gruid.Point {
+	dm := NewDijkstraMap(g.Map.Grid.Size())
+	dm.Compute(g.Map, []DijkstraGoal{{P: p}})
+	tiles := []gruid.Point{}
+	for i, d := range dm.dist {
+		if q := dm.point(i); d > 0 && d <= 10*radius && g.ECS.NoBlockingEntityAt(q) {
+			tiles = append(tiles, q)
+		}
+	}
+	return tiles
+}
+
+// packmates returns the other members of e's pack that still have an AI.
+func (ecs *ECS) packmates(e Entity) []Entity {
+	pk, ok := Get[Pack](ecs, e)
+	if !ok {
+		return nil
+	}
+	mates := []Entity{}
+	for _, m := range ecs.EntitiesWith(Pack{}, AI{}) {
+		if m != e && GetComponent[Pack](ecs, m).leader == pk.leader {
+			mates = append(mates, m)
+		}
+	}
+	return mates
+}
+
+// packLeader returns the position of the leader of e's pack, unless e is the
+// leader, or the leader is dead.
+func (ecs *ECS) packLeader(e Entity) (gruid.Point, bool) {
+	pk, ok := Get[Pack](ecs, e)
+	if !ok || pk.leader == e || !ecs.HasComponents(pk.leader, AI{}, Position{}) {
+		return gruid.Point{}, false
+	}
+	return GetComponent[Position](ecs, pk.leader).Point, true
+}
+
+// alertPack makes the packmates of e, which just spotted target, hunt it too.
+// Sleeping members are woken up.
+func (s *PerceptionSystem) alertPack(e, target Entity) {
+	alerted := false
+	tp := GetComponent[Position](s.ecs, target).Point
+	for _, m := range s.ecs.packmates(e) {
+		ai := GetComponent[AI](s.ecs, m)
+		if ai.state == CSFleeing || (ai.state == CSHunting && ai.target == target) {
+			continue
+		}
+		ai.state, ai.target = CSHunting, target
+		ai.lastSeen = &tp
+		s.ecs.AddComponent(m, ai)
+		alerted = true
+	}
+	pos := GetComponent[Position](s.ecs, e).Point
+	if alerted && s.ecs.Map.VisibleNow[s.ecs.Map.idx(pos)] {
+		name := GetComponent[Name](s.ecs, e).string
+		s.ecs.Create(LogEntry{Text: fmt.Sprintf("The %s shouts a warning!", name), Color: ColorLogSpecial})
+	}
+}
+
+// packSees returns true if some packmate of e currently perceives target.
+func (s *PerceptionSystem) packSees(e, target Entity) bool {
+	for _, m := range s.ecs.packmates(e) {
+		per, ok := Get[Perception](s.ecs, m)
+		if !ok {
+			continue
+		}
+		for _, other := range per.perceived {
+			if other == target {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func scoreRegroup(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if _, ok := s.ecs.packLeader(e); ok && ai.state == CSWandering {
+		return 150
+	}
+	return 0
+}
+
+// actRegroup keeps pack members within PackDistance of their leader.
+func actRegroup(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	lp, _ := s.ecs.packLeader(e)
+	if paths.DistanceChebyshev(p, lp) <= PackDistance || !s.moveTo(e, ai, p, lp) {
+		actWait(s, e, ai, p)
+	}
+}
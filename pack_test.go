@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/paths"
+)
+
+func TestPacks(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	leader := g.NewGoblinChief(gruid.Point{X: 40, Y: 10})
+	group := g.spawnPack(leader, pack{member: (*game).NewGoblin, min: 3, max: 3})
+	if len(group) != 4 {
+		t.Fatalf("pack of %d, want 4", len(group))
+	}
+	for _, e := range group[1:] {
+		if d := paths.DistanceChebyshev(GetComponent[Position](g.ECS, e).Point, gruid.Point{X: 40, Y: 10}); d > PackDistance {
+			t.Errorf("member spawned %d tiles away from its leader", d)
+		}
+	}
+	// When one of them spots the player, the whole pack, even asleep, goes
+	// after them.
+	for _, e := range group {
+		g.ECS.AddComponent(e, AI{state: CSSleeping})
+	}
+	scout := group[1]
+	g.ECS.AddComponents(scout, AI{state: CSWandering}, Position{origin.Shift(5, 0)})
+	h.keys(".")
+	for _, e := range group {
+		if ai := GetComponent[AI](g.ECS, e); ai.state != CSHunting || ai.target != 0 {
+			t.Errorf("pack member %d %s, want %s", e, ai.state, CSHunting)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Ranged attacks by monsters. Archers and casters shoot at their target when
+// they have a clear line of fire, and back off when it gets too close. Shots
+// fly along Bresenham lines, and hit the first entity or wall in their way.
+
+package main
+
+import (
+	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/paths"
+)
+
+// KeepDistance is the distance, in tiles, under which shooters back off from
+// their target.
+const KeepDistance = 3
+
+// ShotStatusTicks is how long the status inflicted by a shot lasts, such as
+// the burning from a firebolt.
+const ShotStatusTicks = 3
+
+// bresenham returns the n points following from on the line going from from
+// through to, extending past to if needed.
+func bresenham(from, to gruid.Point, n int) []gruid.Point {
+	if from == to {
+		return nil
+	}
+	d := to.Sub(from)
+	dx, dy := abs(d.X), -abs(d.Y)
+	sx, sy := sign(d.X), sign(d.Y)
+	err := dx + dy
+	p := from
+	line := make([]gruid.Point, 0, n)
+	for len(line) < n {
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			p.X += sx
+		}
+		if e2 <= dx {
+			err += dx
+			p.Y += sy
+		}
+		line = append(line, p)
+	}
+	return line
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
+
+// clearShot returns true if e, at p, can shoot at q without hitting a wall or
+// an entity it is not hostile to on the way.
+func (ecs *ECS) clearShot(e Entity, p, q gruid.Point) bool {
+	for _, r := range bresenham(p, q, paths.DistanceChebyshev(p, q)) {
+		if !ecs.Map.Walkable(r) {
+			return false
+		}
+		if r == q {
+			break
+		}
+		for _, other := range ecs.EntitiesAtPWith(r, ObstructsMovement{}) {
+			if ecs.Relation(e, other) != Hostile {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// flightLine returns the points a missile flies through from p toward
+// target, up to rng tiles away, stopping before walls.
+func (ecs *ECS) flightLine(p, target gruid.Point, rng int) []gruid.Point {
+	line := []gruid.Point{}
+	for _, q := range bresenham(p, target, min(paths.DistanceChebyshev(p, target), rng)) {
+		if !ecs.Map.Walkable(q) {
+			break
+		}
+		line = append(line, q)
+	}
+	return line
+}
+
+// backOff returns the neighbor of p farthest from tp, if it is farther than
+// p itself.
+func (s *AISystem) backOff(p, tp gruid.Point) (gruid.Point, bool) {
+	best, bestd := p, paths.DistanceChebyshev(p, tp)
+	for _, q := range s.aip.Neighbors(p) {
+		if qd := paths.DistanceChebyshev(q, tp); qd > bestd && s.ecs.NoBlockingEntityAt(q) {
+			best, bestd = q, qd
+		}
+	}
+	return best, best != p
+}
+
+// canShoot returns true if the shooter e at p has the target at tp in range,
+// but not adjacent, and a clear shot at it.
+func (s *AISystem) canShoot(e Entity, p, tp gruid.Point) bool {
+	d := paths.DistanceChebyshev(p, tp)
+	return d > 1 && d <= GetComponent[Shooter](s.ecs, e).rng && s.ecs.clearShot(e, p, tp)
+}
+
+// scoreSkirmish scores shooting at the target, or backing off if it is too
+// close. Shooters that can do neither close in, as other hunting mobs do.
+func scoreSkirmish(s *AISystem, e Entity, ai *AI, p gruid.Point) int {
+	if ai.state != CSHunting || !s.ecs.HasComponent(e, Shooter{}) {
+		return 0
+	}
+	tp, ok := s.targetPos(ai)
+	if !ok {
+		return 0
+	}
+	if _, ok := s.backOff(p, tp); ok && paths.DistanceChebyshev(p, tp) < KeepDistance {
+		return 650
+	}
+	if s.canShoot(e, p, tp) {
+		return 650
+	}
+	return 0
+}
+
+func actSkirmish(s *AISystem, e Entity, ai *AI, p gruid.Point) {
+	tp, _ := s.targetPos(ai)
+	ai.dest = nil
+	if q, ok := s.backOff(p, tp); ok && paths.DistanceChebyshev(p, tp) < KeepDistance {
+		s.ecs.AddComponent(e, Bump{q.Sub(p)})
+		return
+	}
+	s.ecs.AddComponent(e, Shoot{tp})
+}
+
+type ShootSystem struct {
+	ecs *ECS
+}
+
+// Update fires the shot of an entity with Shoot{} and Shooter{} components.
+// The missile flies toward the aimed position, up to the shooter's range, and
+// hits the first entity with health on its way, or stops at a wall. A missile
+// that misses flies on past.
+func (s *ShootSystem) Update(e Entity) {
+	if !s.ecs.HasComponents(e, Shoot{}, Shooter{}, Position{}) {
+		return
+	}
+	aim := GetComponent[Shoot](s.ecs, e).Point
+	s.ecs.RemoveComponent(e, Shoot{})
+	sh := GetComponent[Shooter](s.ecs, e)
+	p := GetComponent[Position](s.ecs, e).Point
+	dmg := GetComponent[Damage](s.ecs, e)
+	flight := []gruid.Point{}
+	for _, q := range bresenham(p, aim, sh.rng) {
+		if !s.ecs.Map.Walkable(q) {
+			break
+		}
+		flight = append(flight, q)
+		victims := s.ecs.EntitiesAtPWith(q, Health{}, ObstructsMovement{})
+		if len(victims) == 0 {
+			continue
+		}
+		if s.ecs.attack(e, victims[0], dmg, sh.missile) {
+			if sh.status != "" {
+				s.ecs.AddStatus(victims[0], sh.status, ShotStatusTicks, 1)
+			}
+			break
+		}
+	}
+	s.ecs.Create(Projectile{from: p, path: flight, glyph: sh.glyph, color: sh.color})
+	s.ecs.Spend(e, CostAttack)
+}
+
+// glyphAt returns the glyph of the projectile at the i-th point of its path.
+// Projectiles without a glyph of their own, such as arrows, point where they
+// fly.
+func (pr Projectile) glyphAt(i int) rune {
+	if pr.glyph != 0 {
+		return pr.glyph
+	}
+	prev := pr.from
+	if i > 0 {
+		prev = pr.path[i-1]
+	}
+	d := pr.path[i].Sub(prev)
+	switch {
+	case d.Y == 0:
+		return '-'
+	case d.X == 0:
+		return '|'
+	case d.X == d.Y:
+		return '\\'
+	}
+	return '/'
+}
+
+// collectAnimations turns the projectiles fired and the blasts set off since
+// the last action into an interruptible animation, showing them one after the
+// other as they fly or spread through the player's field of view.
+func (m *model) collectAnimations() {
+	frames := []Frame{}
+	for _, e := range m.game.ECS.EntitiesWith(Projectile{}) {
+		pr := GetComponent[Projectile](m.game.ECS, e)
+		m.game.ECS.Delete(e)
+		for i, p := range pr.path {
+			if !m.game.InFOV(p) {
+				continue
+			}
+			frames = append(frames, Frame{
+				nticks:     1,
+				framecells: []FrameCell{{NewRenderableNoBg(pr.glyphAt(i), pr.color, ROActor), p}},
+			})
+		}
+	}
+	for _, e := range m.game.ECS.EntitiesWith(Burst{}) {
+		b := GetComponent[Burst](m.game.ECS, e)
+		m.game.ECS.Delete(e)
+		frames = append(frames, m.game.burst(b.center, b.area, b.color)...)
+	}
+	m.animate(frames)
+}
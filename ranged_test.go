@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/paths"
+)
+
+func TestBresenham(t *testing.T) {
+	p := func(x, y int) gruid.Point { return gruid.Point{X: x, Y: y} }
+	tests := []struct {
+		name     string
+		from, to gruid.Point
+		n        int
+		want     []gruid.Point
+	}{
+		{"horizontal", p(0, 0), p(3, 0), 3, []gruid.Point{p(1, 0), p(2, 0), p(3, 0)}},
+		{"diagonal", p(0, 0), p(-2, 2), 2, []gruid.Point{p(-1, 1), p(-2, 2)}},
+		{"shallow", p(0, 0), p(3, 1), 3, []gruid.Point{p(1, 0), p(2, 1), p(3, 1)}},
+		{"past target", p(0, 0), p(0, -1), 3, []gruid.Point{p(0, -1), p(0, -2), p(0, -3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bresenham(tt.from, tt.to, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRangedMonsters(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	// An archer in range shoots, and the arrow is animated.
+	archer := g.NewGoblinArcher(origin.Shift(5, 0))
+	h.keys(".")
+	if !h.logged("arrow hits you") && !h.logged("arrow misses you") {
+		t.Fatalf("archer did not shoot: %v", g.Log)
+	}
+	if h.m.ianimation == nil {
+		t.Errorf("no projectile animation")
+	}
+	if pos := GetComponent[Position](g.ECS, archer).Point; pos != origin.Shift(5, 0) {
+		t.Errorf("archer moved to %v instead of shooting", pos)
+	}
+	// Too close, it backs off.
+	g.ECS.AddComponent(archer, Position{origin.Shift(1, 0)})
+	h.keys(".")
+	if d := paths.DistanceChebyshev(origin, GetComponent[Position](g.ECS, archer).Point); d != 2 {
+		t.Errorf("archer %d tiles away, want 2", d)
+	}
+	// Walls block the line of fire.
+	g.ECS.AddComponent(archer, Position{origin.Shift(5, 0)})
+	g.Map.Grid.Set(origin.Shift(3, 0), Wall)
+	g.Log = nil
+	h.keys(".")
+	if h.logged("arrow") {
+		t.Errorf("archer shot through a wall: %v", g.Log)
+	}
+}
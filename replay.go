@@ -0,0 +1,114 @@
+// Recording and playback of runs. Every action that changes the game state is
+// recorded along with the seed of the run, which is enough to play the run
+// back exactly: see game.Initialize and action.recordable.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+// ReplayVersion is bumped whenever the replay format or the game rules change
+// in a way that breaks older replays.
+const ReplayVersion = 22
+
+// Replay is the content of a replay file.
+type Replay struct {
+	Version int
+	Seed    uint64
+	Actions []action
+}
+
+// SaveReplay writes a replay of the game so far to path.
+func (g *game) SaveReplay(path string) error {
+	data, err := json.Marshal(Replay{
+		Version: ReplayVersion,
+		Seed:    g.Seed,
+		Actions: g.History,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadReplay reads the replay file at path.
+func LoadReplay(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replay{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	if r.Version != ReplayVersion {
+		return nil, fmt.Errorf("replay version %d, expected %d", r.Version, ReplayVersion)
+	}
+	return r, nil
+}
+
+// msgReplayStep asks the model to play the next action of the replay.
+type msgReplayStep struct{}
+
+// updateReplay replaces Update while a replay is being played back. Player
+// input is ignored, except for quitting.
+func (m *model) updateReplay(msg gruid.Msg) gruid.Effect {
+	switch msg := msg.(type) {
+	case gruid.MsgInit:
+		m.game.Seed = m.replay.Seed
+		m.game.Initialize()
+		if m.replayDelay > 0 {
+			return gruid.Batch(frameTicker(), m.nextReplayStep())
+		}
+		// No delay: play the whole replay at once.
+		for m.replayNext < len(m.replay.Actions) {
+			m.playReplayAction()
+		}
+		m.game.Logf("End of replay.", ColorLogSpecial)
+		return frameTicker()
+
+	case msgReplayStep:
+		if m.replayNext >= len(m.replay.Actions) {
+			m.game.Logf("End of replay.", ColorLogSpecial)
+			return nil
+		}
+		m.playReplayAction()
+		return m.nextReplayStep()
+
+	case msgTick:
+		m.handleMsgTick()
+
+	case gruid.MsgKeyDown:
+		switch msg.Key {
+		case "q", gruid.KeyEscape:
+			return m.quit()
+		}
+	}
+	return nil
+}
+
+// playReplayAction plays the next recorded action through handleAction, just
+// like if it came from the player.
+func (m *model) playReplayAction() {
+	m.action = m.replay.Actions[m.replayNext]
+	m.replayNext++
+	m.handleAction()
+}
+
+func (m *model) nextReplayStep() gruid.Cmd {
+	d := m.replayDelay
+	return func() gruid.Msg {
+		time.Sleep(d)
+		return msgReplayStep{}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+// levelsOf returns the saved form of the levels of g, along with the turn
+// and depth, for comparing games.
+func levelsOf(t *testing.T, g *game) []byte {
+	t.Helper()
+	path := t.TempDir() + "/save.json"
+	if err := g.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sf saveFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		t.Fatal(err)
+	}
+	data, err = json.Marshal(struct {
+		Turn, Depth int
+		Levels      []savedLevel
+	}{sf.Turn, sf.Depth, sf.Levels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestSeed checks that a seed always generates the same game.
+func TestSeed(t *testing.T) {
+	a, b, c := newHarness(t, 7), newHarness(t, 7), newHarness(t, 8)
+	if !bytes.Equal(levelsOf(t, &a.m.game), levelsOf(t, &b.m.game)) {
+		t.Errorf("seed 7 generated two different games")
+	}
+	if bytes.Equal(levelsOf(t, &a.m.game), levelsOf(t, &c.m.game)) {
+		t.Errorf("seeds 7 and 8 generated the same game")
+	}
+}
+
+// TestReplay plays a few turns, records them, and checks that playing the
+// recording back ends in the same game.
+func TestReplay(t *testing.T) {
+	h := newHarness(t, 7)
+	g := &h.m.game
+	for range 5 {
+		h.keys("l", "l", "j", "g", "h", "k", "k", ".", "y", "n", ">")
+	}
+	if len(g.History) == 0 || g.ECS.Turn == 0 {
+		t.Fatalf("%d actions recorded over %d turns", len(g.History), g.ECS.Turn)
+	}
+	path := t.TempDir() + "/replay.json"
+	if err := g.SaveReplay(path); err != nil {
+		t.Fatal(err)
+	}
+	rm := NewModel(gruid.NewGrid(UIWidth, UIHeight), config{ReplayPath: path})
+	if rm.replay == nil {
+		t.Fatalf("replay %s not loaded", path)
+	}
+	rm.Update(gruid.MsgInit{})
+	if rm.replayNext != len(g.History) {
+		t.Fatalf("played %d actions of %d", rm.replayNext, len(g.History))
+	}
+	if !bytes.Equal(levelsOf(t, g), levelsOf(t, &rm.game)) {
+		t.Errorf("replay diverged from the recorded game")
+	}
+	for i, e := range g.Log {
+		if i >= len(rm.game.Log) || rm.game.Log[i].Text != e.Text {
+			t.Fatalf("replay log diverges at entry %d: %q", i, e.Text)
+		}
+	}
+}
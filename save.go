@@ -0,0 +1,788 @@
+// Saving and restoring a game in progress. Games are saved as JSON: for every
+// level visited, the map grid and its exploration and lighting state, the
+// state of the map's random number generator, and every entity with its
+// components; then the message log.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+
+	"codeberg.org/anaseto/gruid"
+	"codeberg.org/anaseto/gruid/rl"
+)
+
+// SaveVersion is bumped whenever the save format changes in an incompatible
+// way. Saves from other versions are rejected.
+const SaveVersion = 18
+
+type saveFile struct {
+	Version int
+	Seed    uint64
+	Turn    int
+	History []action // Actions played so far, so that the replay goes on.
+	Depth   int
+	Levels  []savedLevel
+	Log     []LogEntry
+}
+
+type savedLevel struct {
+	Map savedMap
+	ECS savedECS
+}
+
+type savedMap struct {
+	Size          gruid.Point
+	Cells         []rl.Cell // Row-major map cells.
+	Explored      []bool
+	BakedLightMap []float32
+	Rand          []byte // Binary state of the map's PCG source.
+}
+
+type savedECS struct {
+	Entities   []Entity
+	Gens       []uint32 // Generation of each entity index.
+	Free       []uint32
+	Components map[string]json.RawMessage // Keyed by component type name.
+}
+
+// userFile returns the location of the named file in the user's
+// configuration directory, or "" if there is none (e.g. in the browser).
+func userFile(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "grogue", name)
+}
+
+// defaultSavePath returns the default save file location.
+func defaultSavePath() string {
+	return userFile("save.json")
+}
+
+// resume starts the game: the saved game is restored if there is one, and a
+// new game is generated otherwise. The save file is removed once loaded, so
+// that a run can only be resumed once.
+func (m *model) resume() {
+	// A seed given on the command line asks for a new run.
+	if m.savePath != "" && m.game.Seed == 0 {
+		err := m.game.Load(m.savePath)
+		if err == nil {
+			if err := RemoveSave(m.savePath); err != nil {
+				log.Printf("removing save: %v", err)
+			}
+			m.game.Logf("Welcome back.", ColorLogSpecial)
+			return
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("loading save: %v", err)
+		}
+	}
+	m.game.Initialize()
+}
+
+// quit saves the game if the player is still alive, and ends the
+// application. Dead players have nothing to resume, so their save is removed.
+func (m *model) quit() gruid.Effect {
+	if m.game.ECS == nil {
+		return gruid.End()
+	}
+	if m.recordPath != "" {
+		if err := m.game.SaveReplay(m.recordPath); err != nil {
+			log.Printf("saving replay: %v", err)
+		}
+	}
+	if m.savePath == "" {
+		return gruid.End()
+	}
+	var err error
+	if m.game.ECS.PlayerDead() {
+		err = RemoveSave(m.savePath)
+	} else {
+		err = m.game.Save(m.savePath)
+	}
+	if err != nil {
+		log.Printf("saving: %v", err)
+	}
+	return gruid.End()
+}
+
+// Save writes the game to path.
+func (g *game) Save(path string) error {
+	sf := saveFile{
+		Version: SaveVersion,
+		Seed:    g.Seed,
+		Turn:    g.ECS.Turn,
+		History: g.History,
+		Depth:   g.Depth,
+		Log:     g.Log,
+	}
+	for _, lvl := range g.Levels {
+		sm, err := lvl.Map.save()
+		if err != nil {
+			return err
+		}
+		se, err := lvl.ECS.save()
+		if err != nil {
+			return err
+		}
+		sf.Levels = append(sf.Levels, savedLevel{Map: sm, ECS: se})
+	}
+	data, err := json.Marshal(sf)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Load replaces the game with the one saved at path.
+func (g *game) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var sf saveFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return err
+	}
+	if sf.Version != SaveVersion {
+		return fmt.Errorf("save file version %d, expected %d", sf.Version, SaveVersion)
+	}
+	if sf.Depth < 1 || sf.Depth > len(sf.Levels) {
+		return fmt.Errorf("depth %d out of range", sf.Depth)
+	}
+	levels := []*level{}
+	for _, sl := range sf.Levels {
+		m, err := loadMap(sl.Map)
+		if err != nil {
+			return err
+		}
+		ecs := NewECS()
+		ecs.Map = m
+		ecs.Turn = sf.Turn
+		if err := ecs.load(sl.ECS); err != nil {
+			return err
+		}
+		levels = append(levels, &level{Map: m, ECS: ecs})
+	}
+	g.Levels = levels
+	g.Depth = sf.Depth
+	g.Map = levels[g.Depth-1].Map
+	g.ECS = levels[g.Depth-1].ECS
+	g.Log = sf.Log
+	g.Seed = sf.Seed
+	g.History = sf.History
+	// Recompute what the player sees, which is not saved.
+	g.ECS.FOVSystem.Update(0)
+	g.ECS.LightingSystem.UpdateLighting()
+	return nil
+}
+
+// RemoveSave deletes the save file at path, if any.
+func RemoveSave(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (m *Map) save() (savedMap, error) {
+	state, err := m.src.MarshalBinary()
+	if err != nil {
+		return savedMap{}, err
+	}
+	sm := savedMap{
+		Size:          m.Grid.Size(),
+		Explored:      m.Explored,
+		BakedLightMap: m.BakedLightMap,
+		Rand:          state,
+	}
+	it := m.Grid.Iterator()
+	for it.Next() {
+		sm.Cells = append(sm.Cells, it.Cell())
+	}
+	return sm, nil
+}
+
+func loadMap(sm savedMap) (*Map, error) {
+	n := sm.Size.X * sm.Size.Y
+	if len(sm.Cells) != n || len(sm.Explored) != n || len(sm.BakedLightMap) != n {
+		return nil, errors.New("corrupted map in save file")
+	}
+	src := &rand.PCG{}
+	if err := src.UnmarshalBinary(sm.Rand); err != nil {
+		return nil, err
+	}
+	m := newMap(sm.Size, src)
+	it := m.Grid.Iterator()
+	for i := 0; it.Next(); i++ {
+		it.SetCell(sm.Cells[i])
+	}
+	copy(m.Explored, sm.Explored)
+	copy(m.BakedLightMap, sm.BakedLightMap)
+	return m, nil
+}
+
+func (ecs *ECS) save() (savedECS, error) {
+	se := savedECS{
+		Entities:   ecs.entities,
+		Free:       ecs.free,
+		Components: map[string]json.RawMessage{},
+	}
+	for _, slot := range ecs.slots {
+		se.Gens = append(se.Gens, slot.gen)
+	}
+	for _, s := range ecs.stores {
+		data, err := s.marshal()
+		if err != nil {
+			return savedECS{}, err
+		}
+		se.Components[s.typeName()] = data
+	}
+	return se, nil
+}
+
+// load restores saved entities and components into an empty ECS.
+func (ecs *ECS) load(se savedECS) error {
+	ecs.slots = make([]entitySlot, len(se.Gens))
+	for i, gen := range se.Gens {
+		ecs.slots[i].gen = gen
+	}
+	for _, e := range se.Entities {
+		if e.Index() >= len(ecs.slots) {
+			return fmt.Errorf("entity %v out of range", e)
+		}
+		ecs.slots[e.Index()].alive = true
+	}
+	ecs.entities = se.Entities
+	ecs.free = se.Free
+	for _, s := range ecs.stores {
+		data, ok := se.Components[s.typeName()]
+		if !ok {
+			continue // Component type introduced after the save was made.
+		}
+		if err := s.unmarshal(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////
+// JSON encoding of components with unexported fields. Components //
+// with only exported fields use the default encoding.            //
+////////////////////////////////////////////////////////////////////
+
+type jsonRenderable struct {
+	Cell  gruid.Cell
+	Order renderOrder
+}
+
+func (r Renderable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonRenderable{r.cell, r.order})
+}
+
+func (r *Renderable) UnmarshalJSON(data []byte) error {
+	var v jsonRenderable
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	r.cell, r.order = v.Cell, v.Order
+	return nil
+}
+
+func (n Name) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.string)
+}
+
+func (n *Name) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &n.string)
+}
+
+// The FOV itself is not saved: it is recomputed on the next update.
+func (f FOV) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.LOS)
+}
+
+func (f *FOV) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &f.LOS)
+}
+
+type jsonHealth struct {
+	HP, MaxHP int
+}
+
+func (h Health) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonHealth{h.hp, h.maxhp})
+}
+
+func (h *Health) UnmarshalJSON(data []byte) error {
+	var v jsonHealth
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	h.hp, h.maxhp = v.HP, v.MaxHP
+	return nil
+}
+
+func (d Damage) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int{d.min, d.max})
+}
+
+func (d *Damage) UnmarshalJSON(data []byte) error {
+	var v [2]int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	d.min, d.max = v[0], v[1]
+	return nil
+}
+
+func (a Accuracy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.int)
+}
+
+func (a *Accuracy) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &a.int)
+}
+
+func (ev Evasion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ev.int)
+}
+
+func (ev *Evasion) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &ev.int)
+}
+
+// As for FOV, the perception FOV is recomputed rather than saved.
+type jsonPerception struct {
+	LOS       int
+	Perceived []Entity
+	Alertness int
+}
+
+func (p Perception) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonPerception{p.LOS, p.perceived, p.alertness})
+}
+
+func (p *Perception) UnmarshalJSON(data []byte) error {
+	var v jsonPerception
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	p.LOS, p.perceived, p.alertness = v.LOS, v.Perceived, v.Alertness
+	return nil
+}
+
+type jsonAI struct {
+	State      creatureState
+	Dest       *gruid.Point
+	CachedPath []gruid.Point
+	CachedDest *gruid.Point
+	Target     Entity
+	LastSeen   *gruid.Point
+	Search     int
+}
+
+func (ai AI) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAI{ai.state, ai.dest, ai.cachedPath, ai.cachedDest, ai.target, ai.lastSeen, ai.search})
+}
+
+func (ai *AI) UnmarshalJSON(data []byte) error {
+	var v jsonAI
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	ai.state, ai.dest, ai.cachedPath, ai.cachedDest = v.State, v.Dest, v.CachedPath, v.CachedDest
+	ai.target, ai.lastSeen, ai.search = v.Target, v.LastSeen, v.Search
+	return nil
+}
+
+func (d Defense) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.int)
+}
+
+func (d *Defense) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &d.int)
+}
+
+func (eq Equippable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eq.slot)
+}
+
+func (eq *Equippable) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &eq.slot)
+}
+
+func (h Healing) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.amount)
+}
+
+func (h *Healing) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &h.amount)
+}
+
+func (inv Inventory) MarshalJSON() ([]byte, error) {
+	return json.Marshal(inv.items)
+}
+
+func (inv *Inventory) UnmarshalJSON(data []byte) error {
+	inv.items = map[rune]Entity{}
+	return json.Unmarshal(data, &inv.items)
+}
+
+type jsonAction struct {
+	Action actionType
+	Key    rune
+}
+
+func (a Action) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAction{a.action, a.key})
+}
+
+func (a *Action) UnmarshalJSON(data []byte) error {
+	var v jsonAction
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	a.action, a.key = v.Action, v.Key
+	return nil
+}
+
+func (aoe AreaOfEffect) MarshalJSON() ([]byte, error) {
+	return json.Marshal(aoe.radius)
+}
+
+func (aoe *AreaOfEffect) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &aoe.radius)
+}
+
+type jsonDamageEffect struct {
+	Source   Entity
+	Amount   int
+	Missile  string  `json:",omitempty"`
+	Hit      hitKind `json:",omitempty"`
+	Attacker string  `json:",omitempty"`
+	Weapon   string  `json:",omitempty"`
+}
+
+func (de DamageEffect) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonDamageEffect{de.source, de.amount, de.missile, de.hit, de.attacker, de.weapon})
+}
+
+func (de *DamageEffect) UnmarshalJSON(data []byte) error {
+	var v jsonDamageEffect
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	de.source, de.amount, de.missile, de.hit = v.Source, v.Amount, v.Missile, v.Hit
+	de.attacker, de.weapon = v.Attacker, v.Weapon
+	return nil
+}
+
+func (dfx DamageEffects) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dfx.effects)
+}
+
+func (dfx *DamageEffects) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &dfx.effects)
+}
+
+type jsonFrameCell struct {
+	R Renderable
+	P gruid.Point
+}
+
+func (fc FrameCell) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonFrameCell{fc.r, fc.p})
+}
+
+func (fc *FrameCell) UnmarshalJSON(data []byte) error {
+	var v jsonFrameCell
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	fc.r, fc.p = v.R, v.P
+	return nil
+}
+
+type jsonFrame struct {
+	ITick, NTicks int
+	FrameCells    []FrameCell
+}
+
+func (f Frame) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonFrame{f.itick, f.nticks, f.framecells})
+}
+
+func (f *Frame) UnmarshalJSON(data []byte) error {
+	var v jsonFrame
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	f.itick, f.nticks, f.framecells = v.ITick, v.NTicks, v.FrameCells
+	return nil
+}
+
+type jsonAnimation struct {
+	Index, Repeat int
+	Frames        []Frame
+}
+
+func (a Animation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAnimation{a.index, a.repeat, a.frames})
+}
+
+func (a *Animation) UnmarshalJSON(data []byte) error {
+	var v jsonAnimation
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	a.index, a.repeat, a.frames = v.Index, v.Repeat, v.Frames
+	return nil
+}
+
+type jsonStatus struct {
+	Nticks int
+	Power  int `json:",omitempty"`
+}
+
+func (sts Statuses) MarshalJSON() ([]byte, error) {
+	v := map[statusID]jsonStatus{}
+	for id, st := range sts.effects {
+		v[id] = jsonStatus{st.nticks, st.power}
+	}
+	return json.Marshal(v)
+}
+
+func (sts *Statuses) UnmarshalJSON(data []byte) error {
+	var v map[statusID]jsonStatus
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	sts.effects = map[statusID]status{}
+	for id, st := range v {
+		sts.effects[id] = status{nticks: st.Nticks, power: st.Power}
+	}
+	return nil
+}
+
+type jsonEnergy struct {
+	Amount, Speed int
+}
+
+func (en Energy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonEnergy{en.amount, en.speed})
+}
+
+func (en *Energy) UnmarshalJSON(data []byte) error {
+	var v jsonEnergy
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	en.amount, en.speed = v.Amount, v.Speed
+	return nil
+}
+
+func (st Stairs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(st.down)
+}
+
+func (st *Stairs) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &st.down)
+}
+
+type jsonNoise struct {
+	Origin gruid.Point
+	Radius int
+}
+
+func (n Noise) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonNoise{n.origin, n.radius})
+}
+
+func (n *Noise) UnmarshalJSON(data []byte) error {
+	var v jsonNoise
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	n.origin, n.radius = v.Origin, v.Radius
+	return nil
+}
+
+func (f Faction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.faction)
+}
+
+func (f *Faction) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &f.faction)
+}
+
+type jsonFollower struct {
+	Order  Order
+	Target Entity
+}
+
+func (f Follower) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonFollower{f.order, f.target})
+}
+
+func (f *Follower) UnmarshalJSON(data []byte) error {
+	var v jsonFollower
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	f.order, f.target = v.Order, v.Target
+	return nil
+}
+
+func (pk Pack) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pk.leader)
+}
+
+func (pk *Pack) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &pk.leader)
+}
+
+type jsonShooter struct {
+	Range   int
+	Missile string
+	Glyph   rune
+	Color   gruid.Color
+	Status  statusID `json:",omitempty"`
+}
+
+func (sh Shooter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonShooter{sh.rng, sh.missile, sh.glyph, sh.color, sh.status})
+}
+
+func (sh *Shooter) UnmarshalJSON(data []byte) error {
+	var v jsonShooter
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	sh.rng, sh.missile, sh.glyph, sh.color, sh.status = v.Range, v.Missile, v.Glyph, v.Color, v.Status
+	return nil
+}
+
+type jsonProjectile struct {
+	From  gruid.Point
+	Path  []gruid.Point
+	Glyph rune
+	Color gruid.Color
+}
+
+func (pr Projectile) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonProjectile{pr.from, pr.path, pr.glyph, pr.color})
+}
+
+func (pr *Projectile) UnmarshalJSON(data []byte) error {
+	var v jsonProjectile
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	pr.from, pr.path, pr.glyph, pr.color = v.From, v.Path, v.Glyph, v.Color
+	return nil
+}
+
+func (g Goals) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.ids)
+}
+
+func (g *Goals) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &g.ids)
+}
+
+func (g Guard) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.post)
+}
+
+func (g *Guard) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &g.post)
+}
+
+type jsonInflicts struct {
+	Status statusID
+	Ticks  int
+	Power  int
+}
+
+func (in Inflicts) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonInflicts{in.status, in.nticks, in.power})
+}
+
+func (in *Inflicts) UnmarshalJSON(data []byte) error {
+	var v jsonInflicts
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	in.status, in.nticks, in.power = v.Status, v.Ticks, v.Power
+	return nil
+}
+
+type jsonBurst struct {
+	Center gruid.Point
+	Area   []gruid.Point
+	Color  gruid.Color
+}
+
+func (b Burst) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonBurst{b.center, b.area, b.color})
+}
+
+func (b *Burst) UnmarshalJSON(data []byte) error {
+	var v jsonBurst
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	b.center, b.area, b.color = v.Center, v.Area, v.Color
+	return nil
+}
+
+type jsonBolt struct {
+	Missile string
+	Glyph   rune
+	Pierce  bool
+}
+
+func (b Bolt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonBolt{b.missile, b.glyph, b.pierce})
+}
+
+func (b *Bolt) UnmarshalJSON(data []byte) error {
+	var v jsonBolt
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	b.missile, b.glyph, b.pierce = v.Missile, v.Glyph, v.Pierce
+	return nil
+}
+
+type jsonCharges struct {
+	Left, Max int
+	Recharge  int
+	Timer     int
+}
+
+func (c Charges) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonCharges{c.left, c.max, c.recharge, c.timer})
+}
+
+func (c *Charges) UnmarshalJSON(data []byte) error {
+	var v jsonCharges
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	c.left, c.max, c.recharge, c.timer = v.Left, v.Max, v.Recharge, v.Timer
+	return nil
+}
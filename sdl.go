@@ -1,4 +1,4 @@
-//go:build !js
+//go:build !js && !headless && !tcell
 
 package main
 
@@ -0,0 +1,48 @@
+package main
+
+import "codeberg.org/anaseto/gruid"
+
+// spatialIndex maps each map tile to the entities whose Position is on it. It
+// is kept up to date by hooks on the Position store, so that positional
+// queries cost O(entities on the tile) rather than O(entities in the ECS).
+type spatialIndex struct {
+	cells map[gruid.Point][]Entity
+}
+
+func newSpatialIndex() *spatialIndex {
+	return &spatialIndex{cells: make(map[gruid.Point][]Entity)}
+}
+
+// at returns the entities on p. The returned slice must not be modified.
+func (si *spatialIndex) at(p gruid.Point) []Entity {
+	return si.cells[p]
+}
+
+func (si *spatialIndex) add(e Entity, p gruid.Point) {
+	si.cells[p] = append(si.cells[p], e)
+}
+
+func (si *spatialIndex) remove(e Entity, p gruid.Point) {
+	es := remove(si.cells[p], e)
+	if len(es) == 0 {
+		delete(si.cells, p)
+		return
+	}
+	si.cells[p] = es
+}
+
+// onSet is the Position store hook for added or replaced positions.
+func (si *spatialIndex) onSet(e Entity, old Position, had bool, pos Position) {
+	if had {
+		if old.Point == pos.Point {
+			return
+		}
+		si.remove(e, old.Point)
+	}
+	si.add(e, pos.Point)
+}
+
+// onRemove is the Position store hook for removed positions.
+func (si *spatialIndex) onRemove(e Entity, old Position) {
+	si.remove(e, old.Point)
+}
@@ -0,0 +1,259 @@
+// Status effects: timed conditions such as poison or haste. Each status is
+// described once in statusDefs: how it stacks when applied again, what it
+// does every tick and every time its bearer acts, and the messages when it
+// starts and ends. Entities keep their current statuses in a Statuses
+// component, which StatusSystem counts down.
+
+package main
+
+import (
+	"codeberg.org/anaseto/gruid"
+)
+
+type statusID string
+
+const (
+	StatusConfused     statusID = "confused"
+	StatusPoisoned     statusID = "poisoned"
+	StatusBurning      statusID = "burning"
+	StatusHasted       statusID = "hasted"
+	StatusSlowed       statusID = "slowed"
+	StatusParalyzed    statusID = "paralyzed"
+	StatusEntranced    statusID = "entranced"
+	StatusRegenerating statusID = "regenerating"
+	StatusBlind        statusID = "blind"
+	StatusLevitating   statusID = "levitating"
+)
+
+// BlindLOS is how far, in tiles, blind entities still see.
+const BlindLOS = 1
+
+// stacking tells how a status combines with itself when applied again.
+type stacking int
+
+const (
+	stackRefresh   stacking = iota // Keep the longest duration.
+	stackExtend                    // Add up the durations.
+	stackIntensify                 // Add up the powers, keep the longest duration.
+)
+
+// status is a status effect on an entity, lasting for nticks more ticks.
+// What power means depends on the status, e.g. damage per tick for poison.
+type status struct {
+	nticks int
+	power  int
+}
+
+// statusDef describes a kind of status. start and end are the messages for
+// the player, and startThey and endThey the ones for other entities, after
+// their name. tick is called every tick, and act every time the bearer acts,
+// before its action is carried out. Both are optional.
+type statusDef struct {
+	icon             string // Shown next to HP, for the player.
+	color            gruid.Color
+	stack            stacking
+	start, startThey string
+	end, endThey     string
+	tick             func(ecs *ECS, e Entity, st status)
+	act              func(ecs *ECS, e Entity, st status)
+}
+
+// statusOrder is the order in which statuses are updated and listed.
+var statusOrder = []statusID{
+	StatusParalyzed, StatusEntranced, StatusConfused, StatusBlind, StatusPoisoned, StatusBurning,
+	StatusSlowed, StatusHasted, StatusRegenerating, StatusLevitating,
+}
+
+var statusDefs = map[statusID]statusDef{
+	StatusConfused: {
+		icon: "Cnf", color: ColorLogSpecial, stack: stackRefresh,
+		start: "You feel confused.", startThey: "looks confused.",
+		end: "Your confusion fades.", endThey: "is no longer confused.",
+		act: stumble,
+	},
+	StatusPoisoned: {
+		icon: "Psn", color: ColorGrass, stack: stackIntensify,
+		start: "You feel sick.", startThey: "looks sick.",
+		end: "You feel better.", endThey: "looks better.",
+		tick: func(ecs *ECS, e Entity, st status) { ecs.hurt(e, st.power) },
+	},
+	StatusBurning: {
+		icon: "Brn", color: ColorFirebolt, stack: stackRefresh,
+		start: "You catch fire!", startThey: "catches fire!",
+		end: "You are no longer burning.", endThey: "is no longer burning.",
+		tick: func(ecs *ECS, e Entity, st status) { ecs.hurt(e, st.power) },
+	},
+	StatusHasted: {
+		icon: "Hst", color: ColorAlly, stack: stackExtend,
+		start: "You feel yourself speed up.", startThey: "speeds up.",
+		end: "You feel yourself slow down.", endThey: "slows down.",
+	},
+	StatusSlowed: {
+		icon: "Slw", color: ColorWater1, stack: stackExtend,
+		start: "You feel sluggish.", startThey: "slows down.",
+		end: "You feel less sluggish.", endThey: "speeds up.",
+	},
+	StatusParalyzed: {
+		icon: "Par", color: ColorStatusWounded, stack: stackRefresh,
+		start: "You cannot move!", startThey: "is paralyzed!",
+		end: "You can move again.", endThey: "can move again.",
+		act: freeze,
+	},
+	StatusEntranced: {
+		icon: "Ent", color: ColorAlly, stack: stackRefresh,
+		start: "You are entranced!", startThey: "is entranced!",
+		end: "You come to your senses.", endThey: "comes to its senses.",
+		act: freeze,
+	},
+	StatusRegenerating: {
+		icon: "Rgn", color: ColorStatusHealthy, stack: stackRefresh,
+		start: "You feel your wounds closing.", startThey: "starts regenerating.",
+		end: "Your wounds stop closing.", endThey: "stops regenerating.",
+		tick: func(ecs *ECS, e Entity, st status) { ecs.hurt(e, -st.power) },
+	},
+	StatusBlind: {
+		icon: "Bln", color: ColorCorpse, stack: stackRefresh,
+		start: "You are blinded!", startThey: "is blinded!",
+		end: "You can see again.", endThey: "can see again.",
+	},
+	StatusLevitating: {
+		icon: "Lev", color: ColorPlayer, stack: stackExtend,
+		start: "You float into the air.", startThey: "floats into the air.",
+		end: "You float back down.", endThey: "floats back down.",
+	},
+}
+
+// stumble sends confused entities in a random direction.
+func stumble(ecs *ECS, e Entity, st status) {
+	if bump, ok := Get[Bump](ecs, e); ok {
+		bump.Point = Directions[ecs.Map.Rand.IntN(len(Directions))]
+		ecs.AddComponent(e, bump)
+	}
+}
+
+// freeze cancels whatever paralyzed entities meant to do, and makes them wait.
+func freeze(ecs *ECS, e Entity, st status) {
+	ecs.RemoveComponent(e, Bump{})
+	ecs.RemoveComponent(e, Shoot{})
+	ecs.RemoveComponent(e, Action{})
+	ecs.Spend(e, CostWait)
+}
+
+// hurt takes n hit points from e, or gives them back if n is negative, up to
+// its maximum. Deaths are handled as usual by DeathSystem.
+func (ecs *ECS) hurt(e Entity, n int) {
+	h, ok := Get[Health](ecs, e)
+	if !ok {
+		return
+	}
+	h.hp = min(h.hp-n, h.maxhp)
+	ecs.AddComponent(e, h)
+}
+
+// AddStatus puts e under the status id for nticks ticks, with the given
+// power. If e already has it, the two combine according to the status'
+// stacking rule.
+func (ecs *ECS) AddStatus(e Entity, id statusID, nticks, power int) {
+	sts, ok := Get[Statuses](ecs, e)
+	if !ok {
+		sts = Statuses{effects: map[statusID]status{}}
+	}
+	def := statusDefs[id]
+	st := status{nticks: nticks, power: power}
+	old, had := sts.effects[id]
+	if had {
+		switch def.stack {
+		case stackRefresh:
+			st = status{nticks: max(old.nticks, nticks), power: max(old.power, power)}
+		case stackExtend:
+			st = status{nticks: old.nticks + nticks, power: max(old.power, power)}
+		case stackIntensify:
+			st = status{nticks: max(old.nticks, nticks), power: old.power + power}
+		}
+	}
+	sts.effects[id] = st
+	ecs.AddComponent(e, sts)
+	if !had {
+		ecs.report(e, def.start, def.startThey)
+	}
+}
+
+// RemoveStatus cures e of the status id, if it has it.
+func (ecs *ECS) RemoveStatus(e Entity, id statusID) {
+	sts, ok := Get[Statuses](ecs, e)
+	if !ok {
+		return
+	}
+	if _, ok := sts.effects[id]; !ok {
+		return
+	}
+	delete(sts.effects, id)
+	if len(sts.effects) == 0 {
+		ecs.RemoveComponent(e, Statuses{})
+	}
+	def := statusDefs[id]
+	ecs.report(e, def.end, def.endThey)
+}
+
+// HasStatus returns true if e is under the status id.
+func (ecs *ECS) HasStatus(e Entity, id statusID) bool {
+	sts, ok := Get[Statuses](ecs, e)
+	if !ok {
+		return false
+	}
+	_, ok = sts.effects[id]
+	return ok
+}
+
+// sight returns how far e sees, given its line of sight los.
+func (ecs *ECS) sight(e Entity, los int) int {
+	if ecs.HasStatus(e, StatusBlind) {
+		return min(los, BlindLOS)
+	}
+	return los
+}
+
+// statusesOf returns the statuses of e, in statusOrder.
+func (ecs *ECS) statusesOf(e Entity) []statusID {
+	ids := []statusID{}
+	for _, id := range statusOrder {
+		if ecs.HasStatus(e, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+type StatusSystem struct {
+	ecs *ECS
+}
+
+// Update applies the per-tick effects of the statuses of an entity with a
+// Statuses{} component, and counts them down, ending those that run out.
+func (s *StatusSystem) Update(e Entity) {
+	for _, id := range s.ecs.statusesOf(e) {
+		sts, ok := Get[Statuses](s.ecs, e)
+		if !ok {
+			return // e.g. it died.
+		}
+		st := sts.effects[id]
+		if tick := statusDefs[id].tick; tick != nil {
+			tick(s.ecs, e, st)
+		}
+		st.nticks--
+		if st.nticks <= 0 {
+			s.ecs.RemoveStatus(e, id)
+			continue
+		}
+		sts.effects[id] = st
+	}
+}
+
+// Act lets the statuses of an entity that is about to act alter its action.
+func (s *StatusSystem) Act(e Entity) {
+	for _, id := range s.ecs.statusesOf(e) {
+		if act := statusDefs[id].act; act != nil {
+			act(s.ecs, e, GetComponent[Statuses](s.ecs, e).effects[id])
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+func TestStatuses(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	// Poison intensifies when stacked, and hurts every tick.
+	g.ECS.AddStatus(0, StatusPoisoned, 5, 1)
+	g.ECS.AddStatus(0, StatusPoisoned, 3, 1)
+	if st := GetComponent[Statuses](g.ECS, 0).effects[StatusPoisoned]; st != (status{nticks: 5, power: 2}) {
+		t.Errorf("stacked poison %+v, want 5 ticks of 2", st)
+	}
+	h.keys(".")
+	if hp := GetComponent[Health](g.ECS, 0); hp.hp != hp.maxhp-2 {
+		t.Errorf("player hp %d after a poisoned turn, want %d", hp.hp, hp.maxhp-2)
+	}
+	if !h.logged("You feel sick.") {
+		t.Errorf("poisoning not logged: %v", g.Log)
+	}
+	if !strings.Contains(h.line(UIHeight-1), "Psn") {
+		t.Errorf("status line %q does not show the poison", h.line(UIHeight-1))
+	}
+	g.ECS.RemoveStatus(0, StatusPoisoned)
+	// Paralyzed players lose their turns.
+	g.ECS.AddStatus(0, StatusParalyzed, 2, 0)
+	h.keys("l", "l")
+	if h.playerPos() != origin {
+		t.Errorf("paralyzed player moved to %v", h.playerPos())
+	}
+	h.keys("l")
+	if h.playerPos() != origin.Shift(1, 0) || !h.logged("You can move again.") {
+		t.Errorf("player at %v after paralysis: %v", h.playerPos(), g.Log)
+	}
+	// Messages name other entities.
+	goblin := g.NewGoblin(origin.Shift(3, 0))
+	g.ECS.RemoveComponent(goblin, AI{})
+	g.ECS.AddStatus(goblin, StatusConfused, 1, 0)
+	h.keys(".")
+	if !h.logged("The goblin looks confused.") || !h.logged("The goblin is no longer confused.") {
+		t.Errorf("goblin confusion not logged: %v", g.Log)
+	}
+	if h.logged("Your confusion fades.") {
+		t.Errorf("goblin confusion reported as the player's")
+	}
+	// Blind players only see around them.
+	g.ECS.AddStatus(0, StatusBlind, 5, 0)
+	h.keys(".")
+	if g.InFOV(h.playerPos().Shift(2, 0)) || !g.InFOV(h.playerPos().Shift(1, 0)) {
+		t.Errorf("blind player sees too far, or not at all")
+	}
+}
+
+func TestStatusSources(t *testing.T) {
+	tests := []struct {
+		potion func(g *game, p gruid.Point) Entity
+		status statusID
+	}{
+		{(*game).NewPotionOfPoison, StatusPoisoned},
+		{(*game).NewPotionOfParalysis, StatusParalyzed},
+		{(*game).NewPotionOfRegeneration, StatusRegenerating},
+		{(*game).NewPotionOfBlindness, StatusBlind},
+		{(*game).NewPotionOfLevitation, StatusLevitating},
+		{(*game).NewPotionOfHaste, StatusHasted},
+		{(*game).NewPotionOfConfusion, StatusConfused},
+		{(*game).NewPotionOfFire, StatusBurning},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			h := newHarness(t, 1)
+			h.arena(origin)
+			g := &h.m.game
+			// Drinking the potion affects the drinker.
+			tt.potion(g, origin)
+			h.keys("g", "i", "b")
+			if !g.ECS.HasStatus(0, tt.status) {
+				t.Errorf("drinking the potion did not inflict %s", tt.status)
+			}
+			// Throwing it affects whoever it shatters on.
+			goblin := g.NewGoblin(origin.Shift(3, 0))
+			g.ECS.RemoveComponent(goblin, AI{})
+			g.ECS.RemoveComponent(0, Statuses{})
+			tt.potion(g, h.playerPos())
+			h.keys("g", "t", "b", "l", "l", "k", gruid.KeyEnter)
+			if !g.ECS.HasStatus(goblin, tt.status) {
+				t.Errorf("throwing the potion did not inflict %s", tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+//go:build headless
+
+package main
+
+import (
+	"os"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+var driver gruid.Driver
+
+func init() {
+	// Keys are read from the standard input, one per line, and the last
+	// frame is printed on exit. Mostly useful with -replay.
+	dr := NewHeadlessDriver(UIWidth, UIHeight)
+	dr.Input = os.Stdin
+	dr.Output = os.Stdout
+	driver = dr
+}
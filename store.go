@@ -0,0 +1,437 @@
+// Typed component storage for the ECS. Every component type is registered
+// with a componentID, and the ECS keeps one dense store per ID. Looking up a
+// component is then a slice index away, instead of formatting a type name and
+// hashing it into a map of maps.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// componentID identifies a registered component type. It is used to index
+// into ECS.stores.
+type componentID int
+
+const (
+	cPosition componentID = iota
+	cRenderable
+	cName
+	cFOV
+	cInput
+	cBump
+	cObstructsMovement
+	cObstructsView
+	cHealth
+	cDamage
+	cDead
+	cPerception
+	cVisible
+	cAI
+	cLogEntry
+	cConsumable
+	cHealing
+	cCollectible
+	cInventory
+	cRanged
+	cAction
+	cAreaOfEffect
+	cDamageEffect
+	cDamageEffects
+	cAnimation
+	cStatuses
+	cLightSource
+	cEnergy
+	cStairs
+	cNoise
+	cFaction
+	cFollower
+	cCharming
+	cPack
+	cShooter
+	cShoot
+	cProjectile
+	cGoals
+	cEquippable
+	cEquipped
+	cDefense
+	cAccuracy
+	cEvasion
+	cGuard
+	cInflicts
+	cShatters
+	cBurst
+	cBolt
+	cBlink
+	cCharges
+	numComponents // Number of registered component types.
+)
+
+// componentIDOf returns the ID under which the component's type is
+// registered. A type switch is all that is needed here: no reflection or
+// string formatting is involved. New component types must be added both here
+// and in newComponentStores.
+func componentIDOf(c Component) componentID {
+	switch c.(type) {
+	case Position:
+		return cPosition
+	case Renderable:
+		return cRenderable
+	case Name:
+		return cName
+	case FOV:
+		return cFOV
+	case Input:
+		return cInput
+	case Bump:
+		return cBump
+	case ObstructsMovement:
+		return cObstructsMovement
+	case ObstructsView:
+		return cObstructsView
+	case Health:
+		return cHealth
+	case Damage:
+		return cDamage
+	case Dead:
+		return cDead
+	case Perception:
+		return cPerception
+	case Visible:
+		return cVisible
+	case AI:
+		return cAI
+	case LogEntry:
+		return cLogEntry
+	case Consumable:
+		return cConsumable
+	case Healing:
+		return cHealing
+	case Collectible:
+		return cCollectible
+	case Inventory:
+		return cInventory
+	case Ranged:
+		return cRanged
+	case Action:
+		return cAction
+	case AreaOfEffect:
+		return cAreaOfEffect
+	case DamageEffect:
+		return cDamageEffect
+	case DamageEffects:
+		return cDamageEffects
+	case Animation:
+		return cAnimation
+	case Statuses:
+		return cStatuses
+	case LightSource:
+		return cLightSource
+	case Energy:
+		return cEnergy
+	case Stairs:
+		return cStairs
+	case Noise:
+		return cNoise
+	case Faction:
+		return cFaction
+	case Follower:
+		return cFollower
+	case Charming:
+		return cCharming
+	case Pack:
+		return cPack
+	case Shooter:
+		return cShooter
+	case Shoot:
+		return cShoot
+	case Projectile:
+		return cProjectile
+	case Goals:
+		return cGoals
+	case Equippable:
+		return cEquippable
+	case Equipped:
+		return cEquipped
+	case Defense:
+		return cDefense
+	case Accuracy:
+		return cAccuracy
+	case Evasion:
+		return cEvasion
+	case Guard:
+		return cGuard
+	case Inflicts:
+		return cInflicts
+	case Shatters:
+		return cShatters
+	case Burst:
+		return cBurst
+	case Bolt:
+		return cBolt
+	case Blink:
+		return cBlink
+	case Charges:
+		return cCharges
+	}
+	panic(fmt.Sprintf("unregistered component type %T", c))
+}
+
+// newComponentStores allocates one empty store per registered component type,
+// indexed by componentID.
+func newComponentStores() []componentStore {
+	return []componentStore{
+		cPosition:          newStore[Position](),
+		cRenderable:        newStore[Renderable](),
+		cName:              newStore[Name](),
+		cFOV:               newStore[FOV](),
+		cInput:             newStore[Input](),
+		cBump:              newStore[Bump](),
+		cObstructsMovement: newStore[ObstructsMovement](),
+		cObstructsView:     newStore[ObstructsView](),
+		cHealth:            newStore[Health](),
+		cDamage:            newStore[Damage](),
+		cDead:              newStore[Dead](),
+		cPerception:        newStore[Perception](),
+		cVisible:           newStore[Visible](),
+		cAI:                newStore[AI](),
+		cLogEntry:          newStore[LogEntry](),
+		cConsumable:        newStore[Consumable](),
+		cHealing:           newStore[Healing](),
+		cCollectible:       newStore[Collectible](),
+		cInventory:         newStore[Inventory](),
+		cRanged:            newStore[Ranged](),
+		cAction:            newStore[Action](),
+		cAreaOfEffect:      newStore[AreaOfEffect](),
+		cDamageEffect:      newStore[DamageEffect](),
+		cDamageEffects:     newStore[DamageEffects](),
+		cAnimation:         newStore[Animation](),
+		cStatuses:          newStore[Statuses](),
+		cLightSource:       newStore[LightSource](),
+		cEnergy:            newStore[Energy](),
+		cStairs:            newStore[Stairs](),
+		cNoise:             newStore[Noise](),
+		cFaction:           newStore[Faction](),
+		cFollower:          newStore[Follower](),
+		cCharming:          newStore[Charming](),
+		cPack:              newStore[Pack](),
+		cShooter:           newStore[Shooter](),
+		cShoot:             newStore[Shoot](),
+		cProjectile:        newStore[Projectile](),
+		cGoals:             newStore[Goals](),
+		cEquippable:        newStore[Equippable](),
+		cEquipped:          newStore[Equipped](),
+		cDefense:           newStore[Defense](),
+		cAccuracy:          newStore[Accuracy](),
+		cEvasion:           newStore[Evasion](),
+		cGuard:             newStore[Guard](),
+		cInflicts:          newStore[Inflicts](),
+		cShatters:          newStore[Shatters](),
+		cBurst:             newStore[Burst](),
+		cBolt:              newStore[Bolt](),
+		cBlink:             newStore[Blink](),
+		cCharges:           newStore[Charges](),
+	}
+}
+
+// componentStore is the type-erased view of a store, used by the ECS methods
+// that take components as `any`.
+type componentStore interface {
+	has(e Entity) bool
+	getAny(e Entity) (Component, bool)
+	setAny(e Entity, c Component)
+	remove(e Entity)
+	entities() []Entity // Entities holding this component, in storage order.
+	typeName() string
+	marshal() ([]byte, error)
+	unmarshal(data []byte) error
+}
+
+// store is a sparse set holding every component of type T. Components are
+// packed densely in data, with dense[i] being the entity that owns data[i].
+// sparse maps an entity index to its position in dense, plus one, so that the
+// zero value means "absent". Since dense holds full handles, a stale handle
+// whose index has been recycled is never mistaken for the new entity.
+type store[T Component] struct {
+	sparse []int
+	dense  []Entity
+	data   []T
+	name   string
+
+	// Optional hooks, called whenever a component is added, replaced or
+	// removed. They let the ECS maintain indices such as the spatial index.
+	onSet    func(e Entity, old T, had bool, c T)
+	onRemove func(e Entity, old T)
+}
+
+func newStore[T Component]() *store[T] {
+	var zero T
+	return &store[T]{name: fmt.Sprintf("%T", zero)}
+}
+
+func (s *store[T]) index(e Entity) (int, bool) {
+	idx := e.Index()
+	if idx >= len(s.sparse) || s.sparse[idx] == 0 {
+		return 0, false
+	}
+	i := s.sparse[idx] - 1
+	if s.dense[i] != e {
+		return 0, false
+	}
+	return i, true
+}
+
+func (s *store[T]) has(e Entity) bool {
+	_, ok := s.index(e)
+	return ok
+}
+
+func (s *store[T]) get(e Entity) (T, bool) {
+	if i, ok := s.index(e); ok {
+		return s.data[i], true
+	}
+	var zero T
+	return zero, false
+}
+
+// set adds or replaces e's component. It refuses handles whose index is held
+// by another handle, that is stale handles whose index has been reused, which
+// would otherwise take over the live entity's entry.
+func (s *store[T]) set(e Entity, c T) {
+	if i, ok := s.index(e); ok {
+		old := s.data[i]
+		s.data[i] = c
+		if s.onSet != nil {
+			s.onSet(e, old, true, c)
+		}
+		return
+	}
+	if idx := e.Index(); idx < len(s.sparse) && s.sparse[idx] != 0 {
+		return
+	}
+	for e.Index() >= len(s.sparse) {
+		s.sparse = append(s.sparse, 0)
+	}
+	s.dense = append(s.dense, e)
+	s.data = append(s.data, c)
+	s.sparse[e.Index()] = len(s.dense)
+	if s.onSet != nil {
+		var zero T
+		s.onSet(e, zero, false, c)
+	}
+}
+
+// remove deletes e's component by moving the last element into its slot.
+func (s *store[T]) remove(e Entity) {
+	i, ok := s.index(e)
+	if !ok {
+		return
+	}
+	old := s.data[i]
+	last := len(s.dense) - 1
+	moved := s.dense[last]
+	s.dense[i] = moved
+	s.data[i] = s.data[last]
+	s.sparse[moved.Index()] = i + 1
+	s.sparse[e.Index()] = 0
+	var zero T
+	s.data[last] = zero
+	s.dense = s.dense[:last]
+	s.data = s.data[:last]
+	if s.onRemove != nil {
+		s.onRemove(e, old)
+	}
+}
+
+func (s *store[T]) getAny(e Entity) (Component, bool) {
+	if c, ok := s.get(e); ok {
+		return c, true
+	}
+	return nil, false
+}
+
+func (s *store[T]) setAny(e Entity, c Component) {
+	s.set(e, c.(T))
+}
+
+func (s *store[T]) entities() []Entity {
+	return s.dense
+}
+
+func (s *store[T]) typeName() string {
+	return s.name
+}
+
+// storedComponent is the saved form of a single component.
+type storedComponent[T Component] struct {
+	E Entity `json:"e"`
+	C T      `json:"c"`
+}
+
+// marshal encodes every component in the store, in storage order.
+func (s *store[T]) marshal() ([]byte, error) {
+	comps := make([]storedComponent[T], len(s.dense))
+	for i, e := range s.dense {
+		comps[i] = storedComponent[T]{E: e, C: s.data[i]}
+	}
+	return json.Marshal(comps)
+}
+
+// unmarshal decodes components produced by marshal and adds them to the
+// store, going through set so that hooks are run.
+func (s *store[T]) unmarshal(data []byte) error {
+	var comps []storedComponent[T]
+	if err := json.Unmarshal(data, &comps); err != nil {
+		return fmt.Errorf("%s: %w", s.name, err)
+	}
+	for _, c := range comps {
+		s.set(c.E, c.C)
+	}
+	return nil
+}
+
+// storeFor returns the typed store for T.
+func storeFor[T Component](ecs *ECS) *store[T] {
+	var zero T
+	return ecs.stores[componentIDOf(zero)].(*store[T])
+}
+
+// Get returns the T component of an entity, and whether it has one.
+func Get[T Component](ecs *ECS, e Entity) (T, bool) {
+	return storeFor[T](ecs).get(e)
+}
+
+// Set adds a T component to an entity, replacing any existing one. Like
+// AddComponent, it does nothing for entities that are not alive.
+func Set[T Component](ecs *ECS, e Entity, c T) {
+	if !ecs.Alive(e) {
+		return
+	}
+	storeFor[T](ecs).set(e, c)
+}
+
+// Has returns true if the entity holds a T component.
+func Has[T Component](ecs *ECS, e Entity) bool {
+	return storeFor[T](ecs).has(e)
+}
+
+// Query returns the entities holding both a T1 and a T2 component. The
+// smaller of the two stores is scanned.
+func Query[T1, T2 Component](ecs *ECS) (entities []Entity) {
+	s1, s2 := storeFor[T1](ecs), storeFor[T2](ecs)
+	if len(s1.dense) <= len(s2.dense) {
+		for _, e := range s1.dense {
+			if s2.has(e) {
+				entities = append(entities, e)
+			}
+		}
+	} else {
+		for _, e := range s2.dense {
+			if s1.has(e) {
+				entities = append(entities, e)
+			}
+		}
+	}
+	return entities
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+// TestStaleHandles checks that a handle to a deleted entity can neither read
+// nor write the components of the entity that reuses its index.
+func TestStaleHandles(t *testing.T) {
+	ecs := NewECS()
+	stale := ecs.Create(Name{"old"})
+	ecs.Delete(stale)
+	live := ecs.Create(Name{"new"})
+	if live.Index() != stale.Index() {
+		t.Fatalf("index %d not reused, got %d", stale.Index(), live.Index())
+	}
+	if _, ok := Get[Name](ecs, stale); ok {
+		t.Errorf("stale handle reads the live entity's name")
+	}
+	ecs.AddComponent(stale, Name{"stale"})
+	ecs.AddComponents(stale, Health{hp: 1, maxhp: 1})
+	Set(ecs, stale, Accuracy{10})
+	if n := GetComponent[Name](ecs, live).string; n != "new" {
+		t.Errorf("live entity renamed %q through a stale handle", n)
+	}
+	if Has[Health](ecs, live) || Has[Accuracy](ecs, live) {
+		t.Errorf("components added to the live entity through a stale handle")
+	}
+	// The store is left intact: the live entity can still be updated and
+	// removed.
+	ecs.AddComponent(live, Name{"renamed"})
+	if n := GetComponent[Name](ecs, live).string; n != "renamed" {
+		t.Errorf("live entity named %q, want renamed", n)
+	}
+	ecs.Delete(live)
+	if len(ecs.EntitiesWith(Name{})) != 0 {
+		t.Errorf("components left after deleting every entity")
+	}
+}
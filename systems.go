@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"math/rand/v2"
 
 	"codeberg.org/anaseto/gruid"
 	"codeberg.org/anaseto/gruid/paths"
@@ -19,9 +18,14 @@ type PerceptionSystem struct {
 
 // Perception - allows entities with Perception{} and Position{} to perceive
 // other entities within their field of view. If the given entity has an AI
-// component (is a mob) and the player is within its field of view, it will
-// switch to the hunting state.
-func (s *PerceptionSystem) Update(e int) {
+// component (is a mob) and a hostile entity is within its field of view, it
+// will switch to the hunting state, targeting the nearest such entity and
+// remembering where it was seen, or, if it is badly wounded and the target is
+// the player, to the fleeing state. Pack members warn each other, and keep
+// hunting as long as one of them sees the target. Once the target is out of
+// sight, a hunting mob switches to the searching state. Sleeping mobs do not see
+// anything.
+func (s *PerceptionSystem) Update(e Entity) {
 	if !s.ecs.HasComponents(e, Position{}, Perception{}) {
 		return
 	}
@@ -42,7 +46,7 @@ func (s *PerceptionSystem) Update(e int) {
 	passable := func(p gruid.Point) bool {
 		return s.ecs.Map.Grid.At(p) != Wall
 	}
-	per.FOV.SSCVisionMap(pos.Point, per.LOS, passable, true)
+	per.FOV.SSCVisionMap(pos.Point, s.ecs.sight(e, per.LOS), passable, true)
 	for _, other := range s.ecs.EntitiesWith(Position{}, Visible{}) {
 		// Ignore self.
 		if other == e {
@@ -55,28 +59,121 @@ func (s *PerceptionSystem) Update(e int) {
 		}
 	}
 	s.ecs.AddComponent(e, per)
-	// If we're a mob and the player is perceived, switch to hunting state.
+	// If we're a mob and a hostile entity is perceived, hunt the nearest.
 	if s.ecs.HasComponent(e, AI{}) {
-		player_found := false
-		for _, other := range per.perceived {
-			if other == 0 {
-				player_found = true
-				break
-			}
-		}
+		target, found := s.nearestHostile(e, pos.Point, per.perceived)
 		ai := GetComponent[AI](s.ecs, e)
-		if player_found {
+		switch {
+		case ai.state == CSSleeping:
+			// Sleeping mobs see nothing: only noise wakes them up.
+		case found && target == 0 && s.ecs.wounded(e):
+			// The safety map only tells how to flee from the player.
+			if ai.state != CSFleeing {
+				name := GetComponent[Name](s.ecs, e).string
+				s.ecs.Create(LogEntry{Text: fmt.Sprintf("The %s flees!", name), Color: ColorLogSpecial})
+			}
+			ai.state = CSFleeing
+			ai.target = target
+		case found:
 			ai.state = CSHunting
-		} else {
+			ai.target = target
+			p := GetComponent[Position](s.ecs, target).Point
+			ai.lastSeen = &p
+			s.alertPack(e, target)
+		case s.ecs.HasComponent(e, Follower{}):
+			// Followers go back to the player rather than search.
+			ai.state = CSFollowing
+		case ai.state == CSHunting && s.packSees(e, ai.target):
+			// A packmate still sees the target: keep hunting it.
+		case ai.state == CSHunting:
+			// The target just went out of sight: go and look for it
+			// where it was last seen.
+			ai.state = CSSearching
+			ai.search = SearchTurns
+		case ai.state != CSSearching:
 			ai.state = CSWandering
 		}
 		s.ecs.AddComponent(e, ai)
 	}
 }
 
+// nearestHostile returns the closest living entity among the perceived ones
+// that is hostile to e.
+func (s *PerceptionSystem) nearestHostile(e Entity, p gruid.Point, perceived []Entity) (Entity, bool) {
+	var target Entity
+	found := false
+	best := 0
+	for _, other := range perceived {
+		if !s.ecs.HasComponents(other, Position{}, Health{}) || s.ecs.HasComponent(other, Dead{}) {
+			continue
+		}
+		if s.ecs.Relation(e, other) != Hostile {
+			continue
+		}
+		d := paths.DistanceChebyshev(p, GetComponent[Position](s.ecs, other).Point)
+		if !found || d < best {
+			target, best, found = other, d, true
+		}
+	}
+	return target, found
+}
+
+// Noise radii of the player's actions.
+const (
+	NoiseMove   = 2
+	NoiseWall   = 4
+	NoiseUse    = 4 // Using an item, e.g. drinking a potion.
+	NoiseAttack = 8
+)
+
+// Hear lets a mob notice a noise, with a chance depending on its alertness
+// and on how far the noise has to travel to reach it. dm holds the distances
+// from the noise's origin, as computed by ECS.listen. Mobs that notice the
+// noise wake up, if they were asleep, and go look for its origin.
+func (s *PerceptionSystem) Hear(e Entity, n Noise, dm *DijkstraMap) {
+	if !s.ecs.HasComponents(e, Position{}, Perception{}, AI{}) {
+		return
+	}
+	ai := GetComponent[AI](s.ecs, e)
+	if ai.state == CSHunting || ai.state == CSFleeing || ai.state == CSFollowing {
+		return
+	}
+	pos := GetComponent[Position](s.ecs, e).Point
+	reach := 10 * (n.radius + 1)
+	d := dm.At(pos)
+	if d >= reach {
+		return
+	}
+	// The chance decreases linearly with distance. Sleepers are harder to
+	// wake up.
+	chance := GetComponent[Perception](s.ecs, e).alertness * (reach - d) / reach
+	if ai.state == CSSleeping {
+		chance /= 2
+	}
+	if s.ecs.Map.Rand.IntN(100) >= chance {
+		return
+	}
+	visible := s.ecs.Map.VisibleNow[s.ecs.Map.idx(pos)]
+	if ai.state == CSSleeping {
+		name := GetComponent[Name](s.ecs, e).string
+		if visible {
+			s.ecs.Create(LogEntry{Text: fmt.Sprintf("The %s wakes up!", name), Color: ColorLogSpecial})
+		} else {
+			s.ecs.Create(LogEntry{Text: "You hear something stir.", Color: ColorLogSpecial})
+		}
+	}
+	origin := n.origin
+	ai.state = CSSearching
+	ai.target = 0 // Only the player makes noise (see MakeNoise).
+	ai.lastSeen = &origin
+	ai.search = SearchTurns
+	s.ecs.AddComponent(e, ai)
+}
+
 type AISystem struct {
 	ecs *ECS
 	aip *aiPath
+	dm  *DijkstraMap // Scratch map for picking wandering destinations.
 }
 
 type aiPath struct {
@@ -113,53 +210,33 @@ func (aip *aiPath) Estimation(p, q gruid.Point) int {
 	return 10 * paths.DistanceChebyshev(p, q)
 }
 
-func (s *AISystem) Update(e int) {
-	if !s.ecs.HasComponents(e, Position{}, AI{}) {
+type ActionSystem struct {
+	ecs *ECS
+}
+
+// Update performs the item action of an entity with an Action{} component,
+// such as a mob picking up or drinking a potion.
+func (s *ActionSystem) Update(e Entity) {
+	a, ok := Get[Action](s.ecs, e)
+	if !ok {
 		return
 	}
-	ai := GetComponent[AI](s.ecs, e)
-	pos := GetComponent[Position](s.ecs, e)
-	switch ai.state {
-	case CSSleeping:
-		// Do nothing, the entity is asleep!
-		return
-	case CSWandering:
-		// Set a destination, if one is not yet set or we've reached it.
-		if ai.dest == nil || *ai.dest == pos.Point {
-			for {
-				f := s.ecs.Map.RandomFloor()
-				if f != pos.Point {
-					ai.dest = &f
-					break
-				}
-			}
-		}
-	case CSHunting:
-		// Set destination to be the player.
-		pp := GetComponent[Position](s.ecs, 0)
-		ai.dest = &pp.Point
-	}
-	// Recompute A* only when the destination changed or the cached path is
-	// exhausted. Otherwise advance along the existing path one step.
-	destChanged := ai.cachedDest == nil || *ai.cachedDest != *ai.dest
-	if destChanged || len(ai.cachedPath) <= 1 {
-		ai.cachedPath = s.ecs.Map.PR.AstarPath(s.aip, pos.Point, *ai.dest)
-		dest := *ai.dest
-		ai.cachedDest = &dest
-	}
-	if len(ai.cachedPath) > 1 {
-		q := ai.cachedPath[1]
-		ai.cachedPath = ai.cachedPath[1:]
-		s.ecs.AddComponent(e, Bump{q.Sub(pos.Point)})
+	s.ecs.RemoveComponent(e, Action{})
+	switch a.action {
+	case ActionPickup:
+		s.ecs.Pickup(e)
+	case ActionUseItem:
+		s.ecs.UseItem(e, a.key)
+	case ActionDropItem:
+		s.ecs.DropItem(e, a.key)
 	}
-	s.ecs.AddComponent(e, ai)
 }
 
 type BumpSystem struct {
 	ecs *ECS
 }
 
-func (s *BumpSystem) Update(e int) {
+func (s *BumpSystem) Update(e Entity) {
 	if !s.ecs.HasComponents(e, Bump{}, Position{}) {
 		return
 	}
@@ -178,6 +255,10 @@ func (s *BumpSystem) Update(e int) {
 		if len(attackable_entities) == 0 {
 			p.Point = dest // No entity blocking the way, move to dest.
 			s.ecs.AddComponent(e, p)
+			s.ecs.Spend(e, CostMove)
+			if e == 0 && !s.ecs.HasStatus(e, StatusLevitating) {
+				s.ecs.MakeNoise(dest, NoiseMove)
+			}
 			if s.ecs.HasComponent(e, Input{}) {
 				for _, g := range s.ecs.EntitiesAtPWith(dest, ObstructsView{}) {
 					s.ecs.Delete(g)
@@ -188,22 +269,38 @@ func (s *BumpSystem) Update(e int) {
 		if len(attackable_entities) > 1 {
 			panic(fmt.Sprintf("More than one entity with obstruct at position: %v", dest))
 		}
-		// Attack entity at location.
-		dmg := GetComponent[Damage](s.ecs, e)
-		if !s.ecs.HasComponent(attackable_entities[0], DamageEffects{}) {
-			s.ecs.AddComponent(attackable_entities[0], DamageEffects{effects: []DamageEffect{}})
-		}
-		dmgfx := GetComponent[DamageEffects](s.ecs, attackable_entities[0])
 		target_entity := attackable_entities[0]
-		// Add damage effect to the target entity
-		dmgfx.effects = append(dmgfx.effects, DamageEffect{source: e, amount: dmg.int})
-		s.ecs.AddComponent(target_entity, dmgfx)
+		switch s.ecs.Relation(e, target_entity) {
+		case Allied:
+			// Swap places with the ally.
+			tp := GetComponent[Position](s.ecs, target_entity)
+			tp.Point, p.Point = p.Point, dest
+			s.ecs.AddComponent(target_entity, tp)
+			s.ecs.AddComponent(e, p)
+			s.ecs.Spend(e, CostMove)
+			return
+		case Neutral:
+			// Mobs leave neutral entities alone; the player may attack anyone.
+			if !s.ecs.HasComponent(e, Input{}) {
+				return
+			}
+		}
+		// Attack entity at location.
+		s.ecs.attack(e, target_entity, s.ecs.attackPower(e), "")
 		s.ecs.AddComponent(e, p)
+		s.ecs.Spend(e, CostAttack)
+		if e == 0 {
+			s.ecs.MakeNoise(dest, NoiseAttack)
+			s.ecs.assist(target_entity)
+		}
 
 		// s.ecs.AddComponent(target_entity, DamageEffect{source: e, amount: attack_power})
 		// s.ecs.DamageEffectSystem.Update(target_entity)
 	} else {
 		s.ecs.Create(LogEntry{Text: "The wall is firm and unyielding!", Color: ColorLogSpecial})
+		if e == 0 {
+			s.ecs.MakeNoise(p.Point, NoiseWall)
+		}
 	}
 }
 
@@ -212,30 +309,21 @@ type DamageEffectSystem struct {
 	ecs *ECS
 }
 
-func (s *DamageEffectSystem) Update(e int) {
+func (s *DamageEffectSystem) Update(e Entity) {
 	if !s.ecs.HasComponents(e, DamageEffects{}, Health{}) {
 		return
 	}
 	health := GetComponent[Health](s.ecs, e)
 	dmgfx := GetComponent[DamageEffects](s.ecs, e)
 	for _, de := range dmgfx.effects {
-		health.hp -= de.amount
-		name_attacker := GetComponent[Name](s.ecs, de.source).string
-		name_receiver := GetComponent[Name](s.ecs, e).string
-		var msg string
-		if de.source == 0 {
-			msg = fmt.Sprintf("You stab the %s with your sword!", name_receiver)
+		// Armor softens blows, but never quite stops them, and critical
+		// hits find the gaps in it.
+		if de.hit == hitCrit {
+			health.hp -= de.amount
 		} else {
-			if e == 0 {
-				msg = fmt.Sprintf("The %s mauls you!", name_attacker)
-			} else {
-				msg = fmt.Sprintf("The %s hits the %s.", name_attacker, name_receiver)
-			}
-		}
-		msgcolor := ColorLogPlayerAttack
-		if e == 0 {
-			msgcolor = ColorLogMonsterAttack
+			health.hp -= max(de.amount-s.ecs.defense(e), 1)
 		}
+		msg := s.ecs.hitMessage(de, e)
 		if !s.ecs.BloodAt(GetComponent[Position](s.ecs, e).Point) { // Add blood
 			s.ecs.Create(
 				Name{"pool of blood"},
@@ -243,13 +331,21 @@ func (s *DamageEffectSystem) Update(e int) {
 				NewRenderable('.', ColorCorpse, ColorBlood, ROFloor),
 			)
 		}
-		s.ecs.Create(LogEntry{Text: msg, Color: msgcolor})
+		s.ecs.Create(LogEntry{Text: msg, Color: attackColor(e)})
 		if health.hp <= 0 {
 			health.hp = 0
 		}
 	}
 	s.ecs.RemoveComponent(e, DamageEffects{}) // Consume the damage effects.
 	s.ecs.AddComponent(e, health)             // Update health.
+	// Getting hurt wakes sleeping mobs up, and breaks trances.
+	if ai, ok := Get[AI](s.ecs, e); ok && ai.state == CSSleeping && len(dmgfx.effects) > 0 {
+		ai.state = CSWandering
+		s.ecs.AddComponent(e, ai)
+	}
+	if len(dmgfx.effects) > 0 && health.hp > 0 {
+		s.ecs.RemoveStatus(e, StatusEntranced)
+	}
 	// s.printDebug(e) // Debugging output.
 	// Uncomment the following lines to print debug information.
 	// fmt.Printf("Entity: %d\n", e)
@@ -269,7 +365,7 @@ type FOVSystem struct {
 // and mark cells within that FOV as explored. Typically only the player has
 // this component, but other entities such as mobs can have them such as well,
 // such as when the player drinks a potion of telepathy.
-func (s *FOVSystem) Update(e int) {
+func (s *FOVSystem) Update(e Entity) {
 	if !s.ecs.HasComponents(e, Position{}, FOV{}) {
 		return
 	}
@@ -295,8 +391,9 @@ func (s *FOVSystem) Update(e int) {
 		}
 		return len(s.ecs.EntitiesAtPWith(q, ObstructsView{})) == 0
 	}
-	for _, point := range f.FOV.SSCVisionMap(p.Point, f.LOS, isnotwall, true) {
-		if paths.DistanceChebyshev(point, p.Point) > f.LOS {
+	los := s.ecs.sight(e, f.LOS)
+	for _, point := range f.FOV.SSCVisionMap(p.Point, los, isnotwall, true) {
+		if paths.DistanceChebyshev(point, p.Point) > los {
 			continue
 		}
 		idx := s.ecs.Map.idx(point)
@@ -320,7 +417,7 @@ type DeathSystem struct {
 	ecs *ECS
 }
 
-func (s *DeathSystem) Update(e int) {
+func (s *DeathSystem) Update(e Entity) {
 	if !s.ecs.HasComponents(e, Health{}) {
 		return
 	}
@@ -334,10 +431,12 @@ func (s *DeathSystem) Update(e int) {
 	if e == 0 {
 		fov = GetComponent[FOV](s.ecs, e)
 	}
-	var droppedItems []int
+	var droppedItems []Entity
 	if s.ecs.HasComponent(e, Inventory{}) {
-		for _, item := range GetComponent[Inventory](s.ecs, e).items {
-			droppedItems = append(droppedItems, item)
+		inv := GetComponent[Inventory](s.ecs, e)
+		inv.removeStale(s.ecs)
+		for _, k := range sortedInventoryKeys(inv) {
+			droppedItems = append(droppedItems, inv.items[k])
 		}
 	}
 	s.ecs.ClearAllComponents(e) // Clear all components of the entity.
@@ -353,6 +452,7 @@ func (s *DeathSystem) Update(e int) {
 	)
 	for _, item := range droppedItems {
 		s.ecs.AddComponent(item, Position{pos.Point})
+		s.ecs.RemoveComponent(item, Equipped{})
 	}
 	msg := fmt.Sprintf("%s has died!", name)
 	if e == 0 {
@@ -370,7 +470,7 @@ type AnimationSystem struct {
 }
 
 // Updates all Animation objects in the ECS forward a tick.
-func (s *AnimationSystem) Update(e int) {
+func (s *AnimationSystem) Update(e Entity) {
 
 	if !s.ecs.HasComponent(e, Animation{}) {
 		return
@@ -397,29 +497,45 @@ func (s *AnimationSystem) Update(e int) {
 	}
 }
 
-type ConfusedSystem struct {
+// Energy thresholds and costs. An entity with normal speed gains enough
+// energy for one action per tick.
+const (
+	EnergyThreshold = 100 // Energy needed to act.
+	NormalSpeed     = 100 // Energy gained per tick at normal speed.
+
+	CostMove   = 100
+	CostAttack = 100
+	CostWait   = 100
+	CostUse    = 100 // Using an item, e.g. drinking a potion.
+	CostPickup = 50
+	CostDrop   = 50
+)
+
+type EnergySystem struct {
 	ecs *ECS
 }
 
-func (s *ConfusedSystem) Update(e int) {
-	if !s.ecs.HasComponent(e, Confused{}) {
+// Grants an entity the energy of one tick, according to its speed.
+func (s *EnergySystem) Update(e Entity) {
+	if !s.ecs.HasComponent(e, Energy{}) {
 		return
 	}
-	conf := GetComponent[Confused](s.ecs, e)
-	conf.nticks--
-	s.ecs.AddComponent(e, conf)
-	if conf.nticks <= 0 {
-		s.ecs.RemoveComponent(e, Confused{})
-		s.ecs.Create(LogEntry{Text: "Your confusion fades.", Color: ColorLogSpecial})
-	} else {
-		// Randomly change bump direction, if entity has one.
-		if s.ecs.HasComponent(e, Bump{}) {
-			bump := GetComponent[Bump](s.ecs, e)
-			// Randomly change the bump direction.
-			bump.Point = Directions[rand.IntN(len(Directions))]
-			s.ecs.AddComponent(e, bump)
-		}
+	en := GetComponent[Energy](s.ecs, e)
+	en.amount += s.speed(e, en)
+	s.ecs.AddComponent(e, en)
+}
+
+// speed returns the energy gained by an entity per tick, taking haste and
+// slow effects into account. It is never less than 1.
+func (s *EnergySystem) speed(e Entity, en Energy) int {
+	speed := en.speed
+	if s.ecs.HasStatus(e, StatusHasted) {
+		speed *= 2
 	}
+	if s.ecs.HasStatus(e, StatusSlowed) {
+		speed /= 2
+	}
+	return max(speed, 1)
 }
 
 type LightingSystem struct {
@@ -442,8 +558,8 @@ func (s *LightingSystem) BakeTorchLighting() {
 		return s.ecs.Map.Grid.At(p) == Floor
 	}
 	for _, e := range s.ecs.EntitiesWith(Position{}, LightSource{}) {
-		if e == 0 {
-			continue // Player light is dynamic; skip here.
+		if e == 0 || s.ecs.HasComponent(e, Collectible{}) {
+			continue // Player and item lights are dynamic; skip here.
 		}
 		pos := GetComponent[Position](s.ecs, e)
 		ls := GetComponent[LightSource](s.ecs, e)
@@ -499,11 +615,11 @@ func (s *LightingSystem) UpdateLighting() {
 	}
 
 	// Compute only the player's dynamic light source (player moves each turn).
-	if !s.ecs.HasComponents(0, Position{}, LightSource{}) {
+	ls, ok := s.ecs.lightOf(0)
+	if !ok || !s.ecs.HasComponent(0, Position{}) {
 		return
 	}
 	pos := GetComponent[Position](s.ecs, 0)
-	ls := GetComponent[LightSource](s.ecs, 0)
 	visibles := s.fov.SSCVisionMap(pos.Point, ls.Radius, passable, true)
 	for _, p := range visibles {
 		dist := paths.DistanceChebyshev(pos.Point, p)
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+func TestDeath(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	h.m.game.NewTroll(origin.Shift(1, 0))
+	h.ecs().AddComponent(0, Health{hp: 1, maxhp: 18})
+	// The troll is slow, and needs a couple of turns before it can act.
+	for range 3 {
+		h.keys(".")
+	}
+	if !h.ecs().PlayerDead() {
+		t.Fatal("player survived the troll")
+	}
+	if !h.logged("You have died!") {
+		t.Errorf("death not logged: %v", h.m.game.Log)
+	}
+	if status := h.line(UIHeight - 1); !strings.Contains(status, "DEAD") {
+		t.Errorf("status line %q does not show death", status)
+	}
+	// Dead players cannot pick up nor use items anymore.
+	h.keys("g", "i")
+	if h.m.mode != modeNormal {
+		t.Errorf("mode %v after death, want %v", h.m.mode, modeNormal)
+	}
+}
+
+func TestSpeed(t *testing.T) {
+	tests := []struct {
+		name   string
+		status statusID
+		turns  int
+		want   int // Number of troll actions.
+	}{
+		{"normal", "", 10, 8},
+		{"hasted", StatusHasted, 10, 16},
+		{"slowed", StatusSlowed, 10, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, 1)
+			h.arena(origin)
+			troll := h.m.game.NewTroll(origin.Shift(5, 5))
+			h.ecs().RemoveComponent(troll, AI{})
+			if tt.status != "" {
+				h.ecs().AddStatus(troll, tt.status, 100, 0)
+			}
+			// Without AI, the troll only ever waits.
+			for range tt.turns {
+				h.keys(".")
+			}
+			en := GetComponent[Energy](h.ecs(), troll)
+			gained := h.m.game.ECS.Turn * h.ecs().EnergySystem.speed(troll, en)
+			if got := (gained - en.amount) / CostWait; got != tt.want {
+				t.Errorf("troll acted %d times, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActionCosts(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	h.m.game.NewHealthPotion(origin)
+	h.m.game.NewHealthPotion(origin.Shift(1, 0))
+	// Picking up takes half a turn: the energy left over after the first
+	// tick pays for the second pickup.
+	h.keys("g")
+	if got := h.ecs().Turn; got != 1 {
+		t.Errorf("turn %d after one pickup, want 1", got)
+	}
+	h.keys("l", "g")
+	if got := h.ecs().Turn; got != 2 {
+		t.Errorf("turn %d after moving and picking up, want 2", got)
+	}
+	// Failing to use an item takes no time.
+	h.ecs().UseItem(0, 'z')
+	h.ecs().Update()
+	if got := h.ecs().Turn; got != 2 {
+		t.Errorf("turn %d after failing to use an item, want 2", got)
+	}
+}
+
+func TestSleeping(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	// A deaf goblin, asleep right next to the player, does not notice them.
+	goblin := g.NewGoblin(origin.Shift(1, 0))
+	g.ECS.AddComponent(goblin, AI{state: CSSleeping})
+	g.ECS.AddComponent(goblin, Perception{LOS: 8})
+	h.keys(".", ".", "k", "j")
+	if state := GetComponent[AI](g.ECS, goblin).state; state != CSSleeping {
+		t.Fatalf("goblin %s, want %s", state, CSSleeping)
+	}
+	// Hitting it wakes it up.
+	h.keys("l")
+	if state := GetComponent[AI](g.ECS, goblin).state; state == CSSleeping {
+		t.Errorf("goblin still asleep after being hit")
+	}
+}
+
+func TestNoise(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(gruid.Point{X: 1, Y: 10})
+	g := &h.m.game
+	// A wall hides the goblin, but noise goes around it.
+	for y := 9; y <= 11; y++ {
+		g.Map.Grid.Set(gruid.Point{X: 2, Y: y}, Wall)
+	}
+	g.ECS.FOVSystem.Update(0)
+	goblin := g.NewGoblin(gruid.Point{X: 3, Y: 10})
+	g.ECS.AddComponent(goblin, AI{state: CSSleeping})
+	g.ECS.AddComponent(goblin, Perception{LOS: 8, alertness: 10000})
+	// Bumping into the map's edge is noisy, but takes no time: the noise
+	// is heard on the next turn.
+	h.keys("h")
+	if state := GetComponent[AI](g.ECS, goblin).state; state != CSSleeping {
+		t.Fatalf("goblin %s before any time passed", state)
+	}
+	h.keys(".")
+	ai := GetComponent[AI](g.ECS, goblin)
+	if ai.state != CSSearching {
+		t.Errorf("goblin %s, want %s", ai.state, CSSearching)
+	}
+	if !h.logged("You hear something stir.") {
+		t.Errorf("waking up not logged: %v", g.Log)
+	}
+	// Monsters using items make no noise, which would send listeners
+	// after the player.
+	drinker := g.NewGoblin(gruid.Point{X: 5, Y: 10})
+	inv := GetComponent[Inventory](g.ECS, drinker)
+	inv.items['a'] = g.NewHealthPotion(gruid.Point{X: 5, Y: 10})
+	g.ECS.RemoveComponent(inv.items['a'], Position{})
+	g.ECS.AddComponent(drinker, inv)
+	if err := g.ECS.UseItem(drinker, 'a'); err != nil {
+		t.Fatal(err)
+	}
+	if noises := g.ECS.EntitiesWith(Noise{}); len(noises) != 0 {
+		t.Errorf("goblin drinking a potion made %d noises", len(noises))
+	}
+}
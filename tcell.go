@@ -1,42 +1,186 @@
-//go:build !sdl && !js
-// +build !sdl,!js
+//go:build tcell && !js && !headless
+
+// A terminal driver, built directly on tcell. Mouse support is enabled:
+// hovering and clicking work like with the SDL frontend.
 
 package main
 
-// import (
-// 	"codeberg.org/anaseto/gruid"
-// 	tcell "codeberg.org/anaseto/gruid-tcell"
-// 	tc "github.com/gdamore/tcell/v2"
-// )
-
-// var driver gruid.Driver
-
-// func init() {
-// 	st := styler{}
-// 	dr := tcell.NewDriver(tcell.Config{StyleManager: st})
-// 	dr.PreventQuit()
-// 	driver = dr
-// }
-
-// // styler implements the tcell.StyleManager interface.
-// type styler struct{}
-
-// func (sty styler) GetStyle(st gruid.Style) tc.Style {
-// 	ts := tc.StyleDefault
-// 	switch st.Fg {
-// 	case ColorPlayer:
-// 		ts = ts.Foreground(tc.ColorNavy) // blue color for the player
-// 	case ColorFOV:
-// 		ts = ts.Foreground(tc.ColorYellow)
-// 	}
-// 	ts = ts.Background(tc.ColorBlack)
-// 	switch st.Bg {
-// 	case ColorBlood:
-// 		ts = ts.Background(tc.ColorDefault)
-// 	}
-// 	switch st.Attrs {
-// 	case AttrReverse:
-// 		ts = ts.Reverse(true)
-// 	}
-// 	return ts
-// }
+import (
+	"context"
+
+	"codeberg.org/anaseto/gruid"
+	tc "github.com/gdamore/tcell/v2"
+)
+
+var driver gruid.Driver = &TerminalDriver{}
+
+// TerminalDriver implements gruid.Driver on a tcell screen.
+type TerminalDriver struct {
+	screen  tc.Screen
+	buttons tc.ButtonMask // Buttons held at the last mouse event.
+}
+
+func (d *TerminalDriver) Init() error {
+	s, err := tc.NewScreen()
+	if err != nil {
+		return err
+	}
+	if err := s.Init(); err != nil {
+		return err
+	}
+	s.EnableMouse(tc.MouseMotionEvents)
+	s.HideCursor()
+	d.screen = s
+	return nil
+}
+
+func (d *TerminalDriver) PollMsgs(ctx context.Context, ch chan<- gruid.Msg) error {
+	events := make(chan tc.Event, 64)
+	quit := make(chan struct{})
+	defer close(quit)
+	go d.screen.ChannelEvents(events, quit)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			msg := d.msgOf(ev)
+			if msg == nil {
+				continue
+			}
+			select {
+			case ch <- msg:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// msgOf translates a tcell event into a gruid message. It returns nil for
+// events the game does not care about.
+func (d *TerminalDriver) msgOf(ev tc.Event) gruid.Msg {
+	switch ev := ev.(type) {
+	case *tc.EventKey:
+		if ev.Key() == tc.KeyCtrlC {
+			// As closing the SDL window, this quits through the
+			// model, so that the game is saved.
+			return gruid.MsgQuit(ev.When())
+		}
+		key, ok := keyOf(ev)
+		if !ok {
+			return nil
+		}
+		return gruid.MsgKeyDown{Key: key, Mod: modOf(ev.Modifiers()), Time: ev.When()}
+	case *tc.EventMouse:
+		x, y := ev.Position()
+		msg := gruid.MsgMouse{P: gruid.Point{X: x, Y: y}, Mod: modOf(ev.Modifiers()), Time: ev.When()}
+		buttons := ev.Buttons()
+		switch {
+		case buttons&tc.WheelUp != 0:
+			msg.Action = gruid.MouseWheelUp
+		case buttons&tc.WheelDown != 0:
+			msg.Action = gruid.MouseWheelDown
+		case buttons == d.buttons:
+			msg.Action = gruid.MouseMove
+		case buttons&tc.ButtonPrimary != 0:
+			msg.Action = gruid.MouseMain
+		case buttons&tc.ButtonMiddle != 0:
+			msg.Action = gruid.MouseAuxiliary
+		case buttons&tc.ButtonSecondary != 0:
+			msg.Action = gruid.MouseSecondary
+		default:
+			msg.Action = gruid.MouseRelease
+		}
+		d.buttons = buttons &^ (tc.WheelUp | tc.WheelDown)
+		return msg
+	case *tc.EventResize:
+		d.screen.Sync()
+		w, h := ev.Size()
+		return gruid.MsgScreen{Width: w, Height: h, Time: ev.When()}
+	}
+	return nil
+}
+
+// keyOf returns the gruid key for a tcell key event, if any.
+func keyOf(ev *tc.EventKey) (gruid.Key, bool) {
+	switch ev.Key() {
+	case tc.KeyRune:
+		return gruid.Key(ev.Rune()), true
+	case tc.KeyDown:
+		return gruid.KeyArrowDown, true
+	case tc.KeyLeft:
+		return gruid.KeyArrowLeft, true
+	case tc.KeyRight:
+		return gruid.KeyArrowRight, true
+	case tc.KeyUp:
+		return gruid.KeyArrowUp, true
+	case tc.KeyBackspace, tc.KeyBackspace2:
+		return gruid.KeyBackspace, true
+	case tc.KeyDelete:
+		return gruid.KeyDelete, true
+	case tc.KeyEnd:
+		return gruid.KeyEnd, true
+	case tc.KeyEnter:
+		return gruid.KeyEnter, true
+	case tc.KeyEscape:
+		return gruid.KeyEscape, true
+	case tc.KeyHome:
+		return gruid.KeyHome, true
+	case tc.KeyInsert:
+		return gruid.KeyInsert, true
+	case tc.KeyPgDn:
+		return gruid.KeyPageDown, true
+	case tc.KeyPgUp:
+		return gruid.KeyPageUp, true
+	case tc.KeyTab:
+		return gruid.KeyTab, true
+	}
+	return "", false
+}
+
+// modOf returns the gruid modifiers for tcell ones.
+func modOf(mod tc.ModMask) gruid.ModMask {
+	var m gruid.ModMask
+	if mod&tc.ModShift != 0 {
+		m |= gruid.ModShift
+	}
+	if mod&tc.ModCtrl != 0 {
+		m |= gruid.ModCtrl
+	}
+	if mod&tc.ModAlt != 0 {
+		m |= gruid.ModAlt
+	}
+	if mod&tc.ModMeta != 0 {
+		m |= gruid.ModMeta
+	}
+	return m
+}
+
+func (d *TerminalDriver) Flush(frame gruid.Frame) {
+	for _, fc := range frame.Cells {
+		d.screen.SetContent(fc.P.X, fc.P.Y, fc.Cell.Rune, nil, styleOf(fc.Cell.Style))
+	}
+	d.screen.Show()
+}
+
+func (d *TerminalDriver) Close() {
+	if d.screen == nil {
+		return
+	}
+	d.screen.Fini()
+	d.screen = nil
+}
+
+// styleOf maps logical colors to the RGB values of the current theme, as for
+// tiles (see styleColors). On terminals without truecolor support, tcell
+// falls back to the closest color of the 256-color palette.
+func styleOf(st gruid.Style) tc.Style {
+	fg, bg := styleColors(st)
+	return tc.StyleDefault.
+		Foreground(tc.NewRGBColor(int32(fg.R), int32(fg.G), int32(fg.B))).
+		Background(tc.NewRGBColor(int32(bg.R), int32(bg.G), int32(bg.B)))
+}
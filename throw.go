@@ -0,0 +1,98 @@
+// Throwing. Any carried item can be thrown at a position in range. It flies
+// along a Bresenham line, like shots do, and stops before walls or on the
+// first creature it hits, where it lands. Weapons hurt more than other items,
+// and potions shatter on landing, spreading their effects over their area.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+// ThrowRange is how far, in tiles, items can be thrown.
+const ThrowRange = 7
+
+const CostThrow = 100
+
+var (
+	// ErrThrowSelf is returned when throwing an item at one's own position.
+	ErrThrowSelf = errors.New("You cannot throw that at yourself.")
+	// ErrThrowBlocked is returned when a wall right next to the thrower
+	// is in the way.
+	ErrThrowBlocked = errors.New("There is no room to throw that there.")
+)
+
+// thrownDamage returns the damage item deals when thrown at someone. Only
+// weapons are made to hurt.
+func (ecs *ECS) thrownDamage(item Entity) Damage {
+	if eq, ok := Get[Equippable](ecs, item); ok && eq.slot == SlotWeapon {
+		if dmg, ok := Get[Damage](ecs, item); ok {
+			return dmg
+		}
+	}
+	return Damage{1, 1}
+}
+
+// Throw makes e throw the item in its inventory slot key at target. Items
+// that shatter break on the first creature in their way, and others attack
+// it, flying on past if they miss.
+func (ecs *ECS) Throw(e Entity, key rune, target gruid.Point) error {
+	inv := GetComponent[Inventory](ecs, e)
+	it, ok := inv.items[key]
+	if !ok || !ecs.Alive(it) {
+		return ErrNoItem
+	}
+	p := GetComponent[Position](ecs, e).Point
+	if target == p {
+		return ErrThrowSelf
+	}
+	line := ecs.flightLine(p, target, ThrowRange)
+	if len(line) == 0 {
+		return ErrThrowBlocked
+	}
+	delete(inv.items, key)
+	ecs.AddComponent(e, inv)
+	ecs.RemoveComponent(it, Equipped{})
+	name := GetComponent[Name](ecs, it).string
+	ecs.report(e, "You throw the %s.", "throws the %s.", name)
+	shatters := ecs.HasComponent(it, Shatters{})
+	land := p
+	flight := []gruid.Point{}
+	for _, q := range line {
+		flight = append(flight, q)
+		land = q
+		victims := ecs.EntitiesAtPWith(q, Health{}, ObstructsMovement{})
+		if len(victims) == 0 {
+			continue
+		}
+		if shatters || ecs.attack(e, victims[0], ecs.thrownDamage(it), name) {
+			break
+		}
+	}
+	cell := GetComponent[Renderable](ecs, it).cell
+	ecs.Create(Projectile{from: p, path: flight, glyph: cell.Rune, color: cell.Style.Fg})
+	if e == 0 {
+		ecs.MakeNoise(land, NoiseUse)
+	}
+	if shatters {
+		ecs.shatter(e, it, land)
+	} else {
+		ecs.AddComponent(it, Position{land})
+	}
+	ecs.Spend(e, CostThrow)
+	return nil
+}
+
+// shatter breaks item, thrown by e, at p, where it applies its effects to
+// everything in its area of effect.
+func (ecs *ECS) shatter(e, item Entity, p gruid.Point) {
+	if ecs.Map.VisibleNow[ecs.Map.idx(p)] {
+		name := GetComponent[Name](ecs, item).string
+		ecs.Create(LogEntry{Text: fmt.Sprintf("The %s shatters!", name), Color: ColorLogSpecial})
+	}
+	ecs.explode(e, item, p)
+	ecs.Delete(item)
+}
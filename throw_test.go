@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"codeberg.org/anaseto/gruid"
+)
+
+func TestThrow(t *testing.T) {
+	h := newHarness(t, 1)
+	h.arena(origin)
+	g := &h.m.game
+	gob := g.NewGoblin(origin.Shift(3, 0))
+	g.ECS.RemoveComponent(gob, AI{})
+	g.ECS.AddComponent(gob, Health{hp: 20, maxhp: 20})
+	dagger := g.NewDagger(origin)
+	h.keys("g", "t", "b")
+	if h.m.mode != modeTargeting || !h.m.target.throw {
+		t.Fatalf("mode %v, want targeting a throw", h.m.mode)
+	}
+	h.keys("l", "l", "k", gruid.KeyEnter)
+	if !h.logged("You throw the dagger.") {
+		t.Errorf("throw not logged: %v", g.Log)
+	}
+	if _, ok := g.PlayerInventory().items['b']; ok {
+		t.Errorf("thrown dagger still in the inventory")
+	}
+	if pos, ok := Get[Position](g.ECS, dagger); !ok || pos.Point != origin.Shift(3, 0) {
+		t.Errorf("dagger landed at %v, want %v", pos, origin.Shift(3, 0))
+	}
+	if h.m.ianimation == nil {
+		t.Errorf("no flight animation")
+	}
+
+	// Walls stop thrown items.
+	g.Map.Grid.Set(origin.Shift(0, 2), Wall)
+	knife := g.NewDagger(origin)
+	h.keys("g", "t", "b", "h", "j", gruid.KeyEnter)
+	if pos, _ := Get[Position](g.ECS, knife); pos.Point != origin.Shift(0, 1) {
+		t.Errorf("dagger landed at %v, want %v before the wall", pos.Point, origin.Shift(0, 1))
+	}
+
+	// Walls right next to the thrower leave no room to throw.
+	g.Map.Grid.Set(origin.Shift(-1, 0), Wall)
+	fire := g.NewPotionOfFire(origin)
+	h.keys("g")
+	turn := g.ECS.Turn
+	h.keys("t", "b", "h", "h", "k", gruid.KeyEnter)
+	if !h.logged("There is no room to throw that there.") {
+		t.Errorf("throw into a wall not refused: %v", g.Log)
+	}
+	if g.ECS.HasStatus(0, StatusBurning) || !g.ECS.Alive(fire) || g.ECS.Turn != turn {
+		t.Errorf("potion thrown into an adjacent wall")
+	}
+	if got := g.PlayerInventory().items['b']; got != fire {
+		t.Errorf("potion not kept after a refused throw")
+	}
+	inv := g.PlayerInventory()
+	delete(inv.items, 'b')
+	g.ECS.AddComponent(0, inv)
+	g.ECS.Delete(fire)
+	g.Map.Grid.Set(origin.Shift(-1, 0), Floor)
+
+	// Potions shatter, affecting everything around where they land.
+	near := g.NewGoblin(origin.Shift(4, 1))
+	g.ECS.RemoveComponent(near, AI{})
+	potion := g.NewPotionOfConfusion(origin)
+	h.keys("g", "t", "b")
+	if h.m.target.radius != 2 {
+		t.Errorf("potion targeted with radius %d, want 2", h.m.target.radius)
+	}
+	h.keys("l", "l", "l", "k", gruid.KeyEnter)
+	if g.ECS.Alive(potion) {
+		t.Errorf("thrown potion did not shatter")
+	}
+	if !h.logged("The potion of confusion shatters!") {
+		t.Errorf("shattering not logged: %v", g.Log)
+	}
+	for _, e := range []Entity{gob, near} {
+		if !g.ECS.HasStatus(e, StatusConfused) {
+			t.Errorf("goblin %d not confused by the potion", e)
+		}
+	}
+	if g.ECS.HasStatus(0, StatusConfused) {
+		t.Errorf("player confused by a potion thrown out of reach")
+	}
+}
@@ -29,10 +29,16 @@ const (
 	ColorPlayer
 	ColorMonster
 	ColorTroll
+	ColorAlly
+	ColorArrow
+	ColorFirebolt
+	ColorLightning
 
 	ColorCorpse
 	ColorHealthPotion
 	ColorScroll
+	ColorEquipment
+	ColorStairs
 	ColorBlood
 	ColorWater1
 	ColorWater2
@@ -54,8 +60,15 @@ const (
 	ThemeSepia
 )
 
-// Current theme.
-const theme = ThemeSepia
+// Current theme, which may be changed with the -theme flag.
+var theme = ThemeSepia
+
+// themeNames maps the names accepted by -theme to themes.
+var themeNames = map[string]int{
+	"selenized": ThemeSelenized,
+	"noir":      ThemeNoir,
+	"sepia":     ThemeSepia,
+}
 
 type TileDrawer struct {
 	drawer *tiles.Drawer
@@ -94,8 +107,14 @@ var fgTable = map[gruid.Color][3]color.RGBA{
 	ColorFOVDim:           {ThemeNoir: rgba(100, 100, 100), ThemeSepia: rgba(0x50, 0x46, 0x34)},
 	ColorFOVBright:        {ThemeNoir: rgba(255, 255, 210), ThemeSepia: rgba(0xd4, 0xb8, 0x7a)},
 	ColorTroll:            {ThemeNoir: rgba(20, 200, 20), ThemeSepia: rgba(0x30, 0xa0, 0x30)},
+	ColorArrow:            {ThemeSelenized: rgba(0xc8, 0xa0, 0x70), ThemeNoir: rgba(0xc0, 0xc0, 0xc0), ThemeSepia: rgba(0xb0, 0x90, 0x60)},
+	ColorFirebolt:         {ThemeSelenized: rgba(0xff, 0x80, 0x20), ThemeNoir: rgba(0xff, 0x60, 0x00), ThemeSepia: rgba(0xe0, 0x70, 0x20)},
+	ColorLightning:        {ThemeSelenized: rgba(0xf0, 0xe6, 0x8c), ThemeNoir: rgba(255, 255, 160), ThemeSepia: rgba(0xf0, 0xe0, 0x90)},
+	ColorAlly:             {ThemeSelenized: rgba(0x41, 0xc7, 0xb9), ThemeNoir: rgba(0x5f, 0xd7, 0xff), ThemeSepia: rgba(0x70, 0xb0, 0xc0)},
 	ColorHealthPotion:     {ThemeNoir: rgba(0xdb, 0xb3, 0x2d), ThemeSepia: rgba(0xcc, 0x44, 0x44)},
 	ColorScroll:           {ThemeNoir: rgba(0xdb, 0xb3, 0x2d), ThemeSepia: rgba(0xd4, 0xc4, 0x8c)},
+	ColorEquipment:        {ThemeSelenized: rgba(0xad, 0xbc, 0xbc), ThemeNoir: rgba(0xc0, 0xc0, 0xc0), ThemeSepia: rgba(0xb8, 0xa8, 0x88)},
+	ColorStairs:           {ThemeSelenized: rgba(0xdb, 0xb3, 0x2d), ThemeNoir: rgba(255, 255, 255), ThemeSepia: rgba(0xe8, 0xd8, 0xa8)},
 	ColorWater2:           {ThemeNoir: rgba(107, 107, 255), ThemeSepia: rgba(0x30, 0x58, 0x98)},
 	ColorGrass:            {ThemeSelenized: rgba(0x44, 0x99, 0x33), ThemeNoir: rgba(0x44, 0x99, 0x33), ThemeSepia: rgba(0x36, 0x36, 0x36)},
 }
@@ -112,24 +131,30 @@ var bgTable = map[gruid.Color][3]color.RGBA{
 	ColorWater2:    {ThemeNoir: rgba(148, 148, 255), ThemeSepia: rgba(0x18, 0x30, 0x60)},
 }
 
-func (t *TileDrawer) GetImage(c gruid.Cell) image.Image {
+// styleColors returns the foreground and background colors of st in the
+// current theme. It is shared by the tile and terminal frontends.
+func styleColors(st gruid.Style) (fg, bg color.RGBA) {
 	d := themeDefaults[theme]
-	fg := image.NewUniform(d.fg)
-	bg := image.NewUniform(d.bg)
-	if rgba, ok := fgTable[c.Style.Fg]; ok {
+	fg, bg = d.fg, d.bg
+	if rgba, ok := fgTable[st.Fg]; ok {
 		if v := rgba[theme]; v.A != 0 {
-			fg = image.NewUniform(v)
+			fg = v
 		}
 	}
-	if rgba, ok := bgTable[c.Style.Bg]; ok {
+	if rgba, ok := bgTable[st.Bg]; ok {
 		if v := rgba[theme]; v.A != 0 {
-			bg = image.NewUniform(v)
+			bg = v
 		}
 	}
-	if c.Style.Attrs == AttrReverse {
+	if st.Attrs == AttrReverse {
 		fg, bg = bg, fg
 	}
-	return t.drawer.Draw(c.Rune, fg, bg)
+	return fg, bg
+}
+
+func (t *TileDrawer) GetImage(c gruid.Cell) image.Image {
+	fg, bg := styleColors(c.Style)
+	return t.drawer.Draw(c.Rune, image.NewUniform(fg), image.NewUniform(bg))
 }
 
 func (t *TileDrawer) TileSize() gruid.Point {